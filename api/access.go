@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"github.com/paroxity/portal/ban"
+	"github.com/paroxity/portal/session"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// persistentWhitelist returns the whitelist of the proxy if it can be changed at runtime. An error response is
+// written if it cannot, in which case false is returned.
+func (h *Handler) persistentWhitelist(w http.ResponseWriter) (*session.PersistentWhitelist, bool) {
+	wl, ok := h.p.Whitelist().(*session.PersistentWhitelist)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("whitelist %T cannot be changed at runtime", h.p.Whitelist()))
+	}
+	return wl, ok
+}
+
+// whitelist shows if the whitelist is enabled and lists the whitelisted players.
+func (h *Handler) whitelist(w http.ResponseWriter, _ *http.Request) {
+	wl, ok := h.persistentWhitelist(w)
+	if !ok {
+		return
+	}
+	players := wl.Players()
+	if players == nil {
+		players = []session.WhitelistEntry{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Enabled bool                     `json:"enabled"`
+		Players []session.WhitelistEntry `json:"players"`
+	}{Enabled: wl.Enabled(), Players: players})
+}
+
+// setWhitelistEnabled enables or disables the whitelist. Online players that are not whitelisted are disconnected
+// when it is enabled.
+func (h *Handler) setWhitelistEnabled(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled bool `json:"enabled"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if err := h.p.SetWhitelistEnabled(body.Enabled); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.whitelist(w, r)
+}
+
+// addToWhitelist adds a player to the whitelist. If no XUID is passed and the player is online, the XUID of the
+// player is used.
+func (h *Handler) addToWhitelist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+		XUID string `json:"xuid"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	wl, ok := h.persistentWhitelist(w)
+	if !ok {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("player name must not be empty"))
+		return
+	}
+	if s, err := h.findSession(body.Name); body.XUID == "" && err == nil {
+		body.XUID = s.Conn().IdentityData().XUID
+	}
+	if err := wl.Add(body.Name, body.XUID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, session.WhitelistEntry{Name: body.Name, XUID: body.XUID})
+}
+
+// removeFromWhitelist removes a player from the whitelist by their name or XUID. The player is disconnected if
+// they are online and the whitelist is enabled.
+func (h *Handler) removeFromWhitelist(w http.ResponseWriter, r *http.Request) {
+	wl, ok := h.persistentWhitelist(w)
+	if !ok {
+		return
+	}
+	player := r.PathValue("player")
+	removed, err := wl.Remove(player)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	} else if !removed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s is not whitelisted", player))
+		return
+	}
+	if wl.Enabled() {
+		h.p.EnforceWhitelist()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bans lists all bans.
+func (h *Handler) bans(w http.ResponseWriter, _ *http.Request) {
+	bans := h.p.Bans().Bans()
+	if bans == nil {
+		bans = []ban.Ban{}
+	}
+	writeJSON(w, http.StatusOK, bans)
+}
+
+// ban adds a ban and disconnects every online player it applies to. The ban expires after the duration passed, or
+// never if no duration is passed.
+func (h *Handler) ban(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Kind     ban.Kind `json:"kind"`
+		Target   string   `json:"target"`
+		Reason   string   `json:"reason"`
+		Issuer   string   `json:"issuer"`
+		Duration string   `json:"duration"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	b := ban.Ban{Kind: ban.Kind(strings.ToLower(string(body.Kind))), Target: body.Target, Reason: body.Reason, Issuer: body.Issuer, Created: time.Now()}
+	if b.Issuer == "" {
+		b.Issuer = "Admin API"
+	}
+	if body.Duration != "" {
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", body.Duration))
+			return
+		}
+		b.Expires = time.Now().Add(d)
+	}
+	if err := b.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.p.Ban(b); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// unban removes the ban with the kind and target passed.
+func (h *Handler) unban(w http.ResponseWriter, r *http.Request) {
+	kind, target := ban.Kind(strings.ToLower(r.PathValue("kind"))), r.PathValue("target")
+	removed, err := h.p.Unban(kind, target)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	} else if !removed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s %s is not banned", kind, target))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/session"
+	"net/http"
+	"strings"
+)
+
+// TokenCookie is the name of the cookie that may hold the token of the API instead of the Authorization header, so
+// that browsers can authenticate requests such as those of the dashboard.
+const TokenCookie = "portal_token"
+
+// Handler serves the admin API of the proxy. It is an HTTP/JSON API that allows online players, servers, the
+// whitelist and bans to be managed remotely. Every request must carry the token of the API in the Authorization
+// header, in the format of "Bearer <token>", or in the TokenCookie cookie.
+type Handler struct {
+	p        *portal.Portal
+	reloader *portal.ConfigReloader
+	token    string
+	mux      *http.ServeMux
+}
+
+// New creates the admin API of the proxy passed. Requests must be authenticated with the token passed, which must
+// not be empty.
+func New(p *portal.Portal, token string) *Handler {
+	h := &Handler{p: p, token: token, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /api/sessions", h.sessions)
+	h.mux.HandleFunc("GET /api/sessions/{player}", h.session)
+	h.mux.HandleFunc("POST /api/sessions/{player}/transfer", h.transfer)
+	h.mux.HandleFunc("POST /api/sessions/{player}/kick", h.kick)
+
+	h.mux.HandleFunc("GET /api/servers", h.servers)
+	h.mux.HandleFunc("POST /api/servers", h.registerServer)
+	h.mux.HandleFunc("DELETE /api/servers/{name}", h.deregisterServer)
+
+	h.mux.HandleFunc("GET /api/whitelist", h.whitelist)
+	h.mux.HandleFunc("PUT /api/whitelist", h.setWhitelistEnabled)
+	h.mux.HandleFunc("POST /api/whitelist", h.addToWhitelist)
+	h.mux.HandleFunc("DELETE /api/whitelist/{player}", h.removeFromWhitelist)
+
+	h.mux.HandleFunc("GET /api/bans", h.bans)
+	h.mux.HandleFunc("POST /api/bans", h.ban)
+	h.mux.HandleFunc("DELETE /api/bans/{kind}/{target...}", h.unban)
+
+	h.mux.HandleFunc("POST /api/reload", h.reload)
+	return h
+}
+
+// SetReloader sets the reloader used to reload the configuration of the proxy when POST /api/reload is requested.
+// If no reloader is set, reloading is not supported. SetReloader must be called before the handler serves requests.
+func (h *Handler) SetReloader(r *portal.ConfigReloader) {
+	h.reloader = r
+}
+
+// ServeHTTP ...
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.Authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="portal"`)
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// Authorized returns if the request passed carries the token of the API, either in the Authorization header or in
+// the TokenCookie cookie.
+func (h *Handler) Authorized(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return h.ValidToken(token)
+	}
+	c, err := r.Cookie(TokenCookie)
+	return err == nil && h.ValidToken(c.Value)
+}
+
+// ValidToken returns if the token passed is the token of the API.
+func (h *Handler) ValidToken(token string) bool {
+	return h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// findSession finds the session of the online player with the name or UUID passed.
+func (h *Handler) findSession(player string) (*session.Session, error) {
+	store := h.p.SessionStore()
+	if s, ok := store.LoadFromName(player); ok {
+		return s, nil
+	}
+	for _, s := range store.All() {
+		identity := s.Conn().IdentityData()
+		if strings.EqualFold(identity.DisplayName, player) || strings.EqualFold(identity.Identity, player) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("player %s is not online", player)
+}
+
+// readJSON decodes the body of the request passed into the value passed. An error response is written if the body
+// could not be decoded, in which case false is returned.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes the value passed as the JSON body of the response, with the status code passed.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error passed as the JSON body of the response, with the status code passed.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
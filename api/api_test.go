@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testToken is the token that the handlers in these tests are created with.
+const testToken = "secret"
+
+// newTestPortal returns a proxy that does not log anything, with a lobby server and a whitelist saved in a temporary
+// directory.
+func newTestPortal(t *testing.T) *portal.Portal {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	w, err := session.NewPersistentWhitelist(filepath.Join(t.TempDir(), "whitelist.json"), false, []string{"Steve"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return portal.New(portal.Options{
+		Logger:       logging.NewLogrus(l),
+		ListenConfig: minecraft.ListenConfig{StatusProvider: portal.NewMOTDStatusProvider("Portal")},
+		Servers:      []*server.Server{server.NewStatic("lobby", "", "127.0.0.1:19133")},
+		Whitelist:    w,
+	})
+}
+
+// request serves a request with the method, path and body passed, authenticated with testToken, and returns the
+// response.
+func request(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		cookie        string
+		want          int
+	}{
+		{name: "no token", token: testToken, want: http.StatusUnauthorized},
+		{name: "bearer token", token: testToken, authorization: "Bearer secret", want: http.StatusOK},
+		{name: "invalid bearer token", token: testToken, authorization: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "token without scheme", token: testToken, authorization: "secret", want: http.StatusUnauthorized},
+		{name: "cookie", token: testToken, cookie: "secret", want: http.StatusOK},
+		{name: "invalid cookie", token: testToken, cookie: "wrong", want: http.StatusUnauthorized},
+		{name: "invalid bearer token with cookie", token: testToken, authorization: "Bearer wrong", cookie: "secret", want: http.StatusUnauthorized},
+		{name: "empty api token", authorization: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := New(newTestPortal(t), test.token)
+			r := httptest.NewRequest("GET", "/api/servers", nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			if test.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: TokenCookie, Value: test.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != test.want {
+				t.Errorf("status = %d, want %d", rec.Code, test.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("unauthorized response has no WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		want         int
+	}{
+		{name: "list sessions", method: "GET", path: "/api/sessions", want: http.StatusOK},
+		{name: "offline session", method: "GET", path: "/api/sessions/Steve", want: http.StatusNotFound},
+		{name: "transfer offline session", method: "POST", path: "/api/sessions/Steve/transfer", body: `{"server": "lobby"}`, want: http.StatusNotFound},
+		{name: "kick offline session", method: "POST", path: "/api/sessions/Steve/kick", body: `{}`, want: http.StatusNotFound},
+
+		{name: "list servers", method: "GET", path: "/api/servers", want: http.StatusOK},
+		{name: "register server", method: "POST", path: "/api/servers", body: `{"name": "survival", "address": "127.0.0.1:19134"}`, want: http.StatusCreated},
+		{name: "register existing server", method: "POST", path: "/api/servers", body: `{"name": "lobby", "address": "127.0.0.1:19134"}`, want: http.StatusConflict},
+		{name: "register server without name", method: "POST", path: "/api/servers", body: `{"address": "127.0.0.1:19134"}`, want: http.StatusBadRequest},
+		{name: "register server with invalid address", method: "POST", path: "/api/servers", body: `{"name": "survival", "address": "localhost"}`, want: http.StatusBadRequest},
+		{name: "register server with negative weight", method: "POST", path: "/api/servers", body: `{"name": "survival", "address": "127.0.0.1:19134", "weight": -1}`, want: http.StatusBadRequest},
+		{name: "unknown field", method: "POST", path: "/api/servers", body: `{"name": "survival", "port": 19134}`, want: http.StatusBadRequest},
+		{name: "invalid body", method: "POST", path: "/api/servers", body: `{"name": `, want: http.StatusBadRequest},
+		{name: "deregister server", method: "DELETE", path: "/api/servers/lobby", want: http.StatusNoContent},
+		{name: "deregister unknown server", method: "DELETE", path: "/api/servers/survival", want: http.StatusNotFound},
+
+		{name: "whitelist", method: "GET", path: "/api/whitelist", want: http.StatusOK},
+		{name: "enable whitelist", method: "PUT", path: "/api/whitelist", body: `{"enabled": true}`, want: http.StatusOK},
+		{name: "add to whitelist", method: "POST", path: "/api/whitelist", body: `{"name": "Alex"}`, want: http.StatusCreated},
+		{name: "add to whitelist without name", method: "POST", path: "/api/whitelist", body: `{"xuid": "2535400000000001"}`, want: http.StatusBadRequest},
+		{name: "remove from whitelist", method: "DELETE", path: "/api/whitelist/steve", want: http.StatusNoContent},
+		{name: "remove player not whitelisted", method: "DELETE", path: "/api/whitelist/Alex", want: http.StatusNotFound},
+
+		{name: "list bans", method: "GET", path: "/api/bans", want: http.StatusOK},
+		{name: "ban", method: "POST", path: "/api/bans", body: `{"kind": "name", "target": "Steve", "duration": "1h"}`, want: http.StatusCreated},
+		{name: "ban with invalid duration", method: "POST", path: "/api/bans", body: `{"kind": "name", "target": "Steve", "duration": "-1h"}`, want: http.StatusBadRequest},
+		{name: "ban with unknown kind", method: "POST", path: "/api/bans", body: `{"kind": "skin", "target": "Steve"}`, want: http.StatusBadRequest},
+		{name: "ban invalid ip", method: "POST", path: "/api/bans", body: `{"kind": "ip", "target": "10.0.0.0/33"}`, want: http.StatusBadRequest},
+		{name: "unban player not banned", method: "DELETE", path: "/api/bans/name/Steve", want: http.StatusNotFound},
+
+		{name: "reload without reloader", method: "POST", path: "/api/reload", want: http.StatusNotImplemented},
+		{name: "unknown endpoint", method: "GET", path: "/api/players", want: http.StatusNotFound},
+		{name: "method not allowed", method: "PATCH", path: "/api/servers", want: http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := request(New(newTestPortal(t), testToken), test.method, test.path, test.body)
+			if rec.Code != test.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, test.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestUnban(t *testing.T) {
+	h := New(newTestPortal(t), testToken)
+	if rec := request(h, "POST", "/api/bans", `{"kind": "ip", "target": "10.0.0.0/8"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("ban status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	// The target of IP range bans contains a slash, which must still be matched by the route.
+	if rec := request(h, "DELETE", "/api/bans/IP/10.0.0.0/8", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("unban status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+}
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		restart []string
+	}{
+		{name: "unchanged", data: "{}", want: http.StatusOK, restart: []string{}},
+		{name: "restart setting", data: `{"network": {"address": ":19133"}}`, want: http.StatusOK, restart: []string{"network.address"}},
+		{name: "invalid file", data: `{"network": `, want: http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestPortal(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			conf, err := portal.LoadConfig(path, dir, p.Logger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(test.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			h := New(p, testToken)
+			h.SetReloader(portal.NewConfigReloader(p, path, dir, conf))
+
+			rec := request(h, "POST", "/api/reload", "")
+			if rec.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, test.want, rec.Body)
+			}
+			if test.want != http.StatusOK {
+				return
+			}
+			var body struct {
+				Restart []string `json:"restart"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if !reflect.DeepEqual(body.Restart, test.restart) {
+				t.Errorf("restart = %v, want %v", body.Restart, test.restart)
+			}
+		})
+	}
+}
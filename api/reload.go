@@ -0,0 +1,26 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// reload reloads the configuration of the proxy and lists the settings that changed but require a restart to take
+// effect.
+func (h *Handler) reload(w http.ResponseWriter, _ *http.Request) {
+	if h.reloader == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("the configuration cannot be reloaded"))
+		return
+	}
+	restart, err := h.reloader.Reload()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if restart == nil {
+		restart = []string{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Restart []string `json:"restart"`
+	}{Restart: restart})
+}
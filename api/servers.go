@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/server"
+	"net"
+	"net/http"
+	"sort"
+)
+
+// Server is the JSON representation of a server registered on the proxy.
+type Server struct {
+	Name       string `json:"name"`
+	Group      string `json:"group"`
+	Address    string `json:"address"`
+	Static     bool   `json:"static"`
+	Healthy    bool   `json:"healthy"`
+	Players    int    `json:"players"`
+	MaxPlayers int    `json:"max_players"`
+	Weight     int    `json:"weight"`
+	LatencyMs  int64  `json:"latency_ms"`
+}
+
+// newServer returns the JSON representation of the server passed.
+func newServer(srv *server.Server) Server {
+	return Server{
+		Name:       srv.Name(),
+		Group:      srv.Group(),
+		Address:    srv.Address(),
+		Static:     srv.Static(),
+		Healthy:    srv.Healthy(),
+		Players:    srv.PlayerCount(),
+		MaxPlayers: srv.MaxPlayers(),
+		Weight:     srv.Weight(),
+		LatencyMs:  srv.Latency().Milliseconds(),
+	}
+}
+
+// Servers returns the JSON representation of all servers registered on the proxy, sorted by name.
+func Servers(p *portal.Portal) []Server {
+	all := p.ServerRegistry().Servers()
+	servers := make([]Server, 0, len(all))
+	for _, srv := range all {
+		servers = append(servers, newServer(srv))
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers
+}
+
+// servers lists all servers registered on the proxy, sorted by name.
+func (h *Handler) servers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Servers(h.p))
+}
+
+// registerServer registers a new server on the proxy. Servers registered through the API are static, so they are
+// kept until they are deregistered, like the servers in the configuration.
+func (h *Handler) registerServer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name       string `json:"name"`
+		Group      string `json:"group"`
+		Address    string `json:"address"`
+		MaxPlayers int    `json:"max_players"`
+		Weight     int    `json:"weight"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("server name must not be empty"))
+		return
+	}
+	if _, _, err := net.SplitHostPort(body.Address); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q: expected the format \"ip:port\"", body.Address))
+		return
+	}
+	if body.MaxPlayers < 0 || body.Weight < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("max players and weight must not be negative"))
+		return
+	}
+	registry := h.p.ServerRegistry()
+	if _, ok := registry.Server(body.Name); ok {
+		writeError(w, http.StatusConflict, fmt.Errorf("server %s is already registered", body.Name))
+		return
+	}
+
+	srv := server.NewStatic(body.Name, body.Group, body.Address)
+	srv.SetMaxPlayers(body.MaxPlayers)
+	if body.Weight != 0 {
+		srv.SetWeight(body.Weight)
+	}
+	registry.AddServer(srv)
+	h.p.Logger().Infof("server %s has been registered through the admin API in group \"%s\" with the address \"%s\"", srv.Name(), srv.Group(), srv.Address())
+	writeJSON(w, http.StatusCreated, newServer(srv))
+}
+
+// deregisterServer removes a server from the proxy. Players on the server are not moved, but the server is no
+// longer used for new players and transfers.
+func (h *Handler) deregisterServer(w http.ResponseWriter, r *http.Request) {
+	registry := h.p.ServerRegistry()
+	srv, ok := registry.Server(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("server %s not found", r.PathValue("name")))
+		return
+	}
+	registry.RemoveServer(srv)
+	h.p.Logger().Infof("server %s has been deregistered through the admin API", srv.Name())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session is the JSON representation of the session of an online player.
+type Session struct {
+	Name         string `json:"name"`
+	UUID         string `json:"uuid"`
+	XUID         string `json:"xuid"`
+	Server       string `json:"server"`
+	Group        string `json:"group"`
+	LatencyMs    int64  `json:"latency_ms"`
+	Transferring bool   `json:"transferring"`
+
+	// The fields below are only included in the details of a single session.
+	Address     string `json:"address,omitempty"`
+	DeviceOS    int    `json:"device_os,omitempty"`
+	GameVersion string `json:"game_version,omitempty"`
+}
+
+// newSession returns the JSON representation of the session passed. If detailed is true, the address and client
+// information of the player are included.
+func newSession(s *session.Session, detailed bool) Session {
+	identity := s.Conn().IdentityData()
+	v := Session{
+		Name:         identity.DisplayName,
+		UUID:         identity.Identity,
+		XUID:         identity.XUID,
+		LatencyMs:    s.Conn().Latency().Milliseconds(),
+		Transferring: s.Transferring(),
+	}
+	if srv := s.Server(); srv != nil {
+		v.Server, v.Group = srv.Name(), srv.Group()
+	}
+	if detailed {
+		client := s.Conn().ClientData()
+		v.Address = s.Conn().RemoteAddr().String()
+		v.DeviceOS = int(client.DeviceOS)
+		v.GameVersion = client.GameVersion
+	}
+	return v
+}
+
+// Sessions returns the JSON representation of the sessions of all players online on the proxy, sorted by name.
+func Sessions(p *portal.Portal) []Session {
+	all := p.SessionStore().All()
+	sessions := make([]Session, 0, len(all))
+	for _, s := range all {
+		sessions = append(sessions, newSession(s, false))
+	}
+	sort.Slice(sessions, func(i, j int) bool { return strings.ToLower(sessions[i].Name) < strings.ToLower(sessions[j].Name) })
+	return sessions
+}
+
+// sessions lists the sessions of all online players, sorted by name.
+func (h *Handler) sessions(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Sessions(h.p))
+}
+
+// session shows the details of the session of one online player.
+func (h *Handler) session(w http.ResponseWriter, r *http.Request) {
+	s, err := h.findSession(r.PathValue("player"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSession(s, true))
+}
+
+// transfer transfers an online player to another server. The request blocks until the transfer has completed or
+// failed, and the transfer is cancelled if the request is.
+func (h *Handler) transfer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Server string `json:"server"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s, err := h.findSession(r.PathValue("player"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	srv, ok := h.p.ServerRegistry().Server(body.Server)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("server %s not found", body.Server))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+	if err := s.TransferContext(ctx, srv); err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, session.ErrTransferInProgress), errors.Is(err, session.ErrServerFull), errors.Is(err, session.ErrServerUnhealthy):
+			status = http.StatusConflict
+		case errors.Is(err, session.ErrSessionClosed):
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSession(s, false))
+}
+
+// kick disconnects an online player from the proxy with an optional reason.
+func (h *Handler) kick(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	s, err := h.findSession(r.PathValue("player"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "Kicked by an operator"
+	}
+	h.p.Logger().Infof("%s has been kicked through the admin API: %s", s.Conn().IdentityData().DisplayName, body.Reason)
+	s.Disconnect(text.Colourf("<red>%s</red>", body.Reason))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package ban
+
+import (
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"net"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of target a Ban applies to.
+type Kind string
+
+const (
+	// KindUUID bans the player with the UUID of the target.
+	KindUUID Kind = "uuid"
+	// KindXUID bans the player with the XUID of the target.
+	KindXUID Kind = "xuid"
+	// KindName bans the player with the name of the target, case-insensitive.
+	KindName Kind = "name"
+	// KindIP bans all players connecting from the IP address of the target, which may also be a CIDR range.
+	KindIP Kind = "ip"
+)
+
+// Ban is a ban of a player, or of an IP address or range.
+type Ban struct {
+	// Kind is the kind of the target of the ban.
+	Kind Kind `json:"kind"`
+	// Target is the UUID, XUID, name or IP address that is banned, depending on the kind of the ban.
+	Target string `json:"target"`
+	// Reason is the reason of the ban, which is shown to the player when they are disconnected.
+	Reason string `json:"reason"`
+	// Issuer is the name of whoever issued the ban.
+	Issuer string `json:"issuer"`
+	// Created is the time at which the ban was issued.
+	Created time.Time `json:"created"`
+	// Expires is the time at which the ban expires. If it is zero, the ban never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// Validate checks if the ban has a valid kind and target, and returns an error if it does not.
+func (b Ban) Validate() error {
+	if b.Target == "" {
+		return fmt.Errorf("ban has no target")
+	}
+	switch b.Kind {
+	case KindUUID, KindXUID, KindName:
+		return nil
+	case KindIP:
+		if strings.Contains(b.Target, "/") {
+			if _, _, err := net.ParseCIDR(b.Target); err != nil {
+				return fmt.Errorf("invalid CIDR range %q", b.Target)
+			}
+		} else if net.ParseIP(b.Target) == nil {
+			return fmt.Errorf("invalid IP address %q", b.Target)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown ban kind %q", b.Kind)
+}
+
+// Expired returns if the ban has expired at the time passed.
+func (b Ban) Expired(t time.Time) bool {
+	return !b.Expires.IsZero() && !t.Before(b.Expires)
+}
+
+// Matches returns if the ban applies to the player with the identity data passed, connecting from the address
+// passed.
+func (b Ban) Matches(identity login.IdentityData, addr net.Addr) bool {
+	switch b.Kind {
+	case KindUUID:
+		return strings.EqualFold(b.Target, identity.Identity)
+	case KindXUID:
+		return identity.XUID != "" && b.Target == identity.XUID
+	case KindName:
+		return strings.EqualFold(b.Target, identity.DisplayName)
+	case KindIP:
+		ip := addressIP(addr)
+		if ip == nil {
+			return false
+		}
+		if _, network, err := net.ParseCIDR(b.Target); err == nil {
+			return network.Contains(ip)
+		}
+		return ip.Equal(net.ParseIP(b.Target))
+	}
+	return false
+}
+
+// Message returns the message shown to a player that is disconnected because of the ban.
+func (b Ban) Message() string {
+	m := text.Colourf("<red>You are banned from this network.</red>")
+	if b.Reason != "" {
+		m += text.Colourf("\n<grey>Reason: %s</grey>", b.Reason)
+	}
+	if !b.Expires.IsZero() {
+		m += text.Colourf("\n<grey>Expires: %s</grey>", b.Expires.Format(time.RFC1123))
+	}
+	return m
+}
+
+// addressIP returns the IP of the network address passed, or nil if it has none.
+func addressIP(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return net.ParseIP(host)
+}
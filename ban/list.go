@@ -0,0 +1,150 @@
+package ban
+
+import (
+	"encoding/json"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List is a list of bans. Bans may be added and removed at runtime, and are persisted to a JSON file so that
+// they survive restarts.
+type List struct {
+	path string
+
+	mu   sync.Mutex
+	bans []Ban
+}
+
+// NewList creates a new ban list which loads its bans from and saves them to the file at the path passed. If the
+// path is empty, the bans are not persisted.
+func NewList(path string) (*List, error) {
+	l := &List{path: path}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.bans); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Add adds the ban passed to the list and saves the list. If a ban with the same kind and target already exists,
+// it is replaced. If the ban has no creation time, the current time is used.
+func (l *List) Add(b Ban) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if b.Created.IsZero() {
+		b.Created = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bans := append([]Ban(nil), l.bans...)
+	if i, ok := l.index(b.Kind, b.Target); ok {
+		bans[i] = b
+	} else {
+		bans = append(bans, b)
+	}
+	return l.commit(bans)
+}
+
+// Remove removes the ban with the kind and target passed from the list and saves the list. False is returned if no
+// such ban existed.
+func (l *List) Remove(kind Kind, target string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i, ok := l.index(kind, target)
+	if !ok {
+		return false, nil
+	}
+	bans := append(append([]Ban(nil), l.bans[:i]...), l.bans[i+1:]...)
+	return true, l.commit(bans)
+}
+
+// Bans returns all the bans in the list that have not expired.
+func (l *List) Bans() []Ban {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+	return append([]Ban(nil), l.bans...)
+}
+
+// Find returns the first ban that applies to the player with the identity data passed, connecting from the address
+// passed, and if one was found.
+func (l *List) Find(identity login.IdentityData, addr net.Addr) (Ban, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+	for _, b := range l.bans {
+		if b.Matches(identity, addr) {
+			return b, true
+		}
+	}
+	return Ban{}, false
+}
+
+// index returns the index of the ban with the kind and target passed, and if it was found.
+func (l *List) index(kind Kind, target string) (int, bool) {
+	for i, b := range l.bans {
+		if b.Kind == kind && strings.EqualFold(b.Target, target) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// prune removes all expired bans from the list. The list is not saved, as expired bans are harmless and are
+// removed the next time the list is saved.
+func (l *List) prune() {
+	l.bans = unexpired(l.bans, time.Now())
+}
+
+// commit saves the bans passed, without the bans that have expired, and replaces the bans in the list with them.
+// If the bans could not be saved, the list is left unchanged.
+func (l *List) commit(bans []Ban) error {
+	bans = unexpired(bans, time.Now())
+	if err := l.save(bans); err != nil {
+		return err
+	}
+	l.bans = bans
+	return nil
+}
+
+// save saves the bans passed to the file of the list, if it has one.
+func (l *List) save(bans []Ban) error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(bans, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, data, 0644)
+}
+
+// unexpired removes the bans that have expired at the time passed from the bans passed, which are modified, and
+// returns the bans left.
+func unexpired(bans []Ban, t time.Time) []Ban {
+	n := bans[:0]
+	for _, b := range bans {
+		if !b.Expired(t) {
+			n = append(n, b)
+		}
+	}
+	return n
+}
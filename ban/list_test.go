@@ -0,0 +1,91 @@
+package ban
+
+import (
+	"encoding/json"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{name: "permanent", want: false},
+		{name: "future", expires: now.Add(time.Minute), want: false},
+		{name: "now", expires: now, want: true},
+		{name: "past", expires: now.Add(-time.Minute), want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (Ban{Expires: test.expires}).Expired(now); got != test.want {
+				t.Errorf("Expired() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestListExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	now := time.Now()
+	stored := []Ban{
+		{Kind: KindName, Target: "Steve", Created: now.Add(-time.Hour)},
+		{Kind: KindName, Target: "Alex", Created: now.Add(-time.Hour), Expires: now.Add(-time.Minute)},
+		{Kind: KindIP, Target: "10.0.0.0/8", Created: now.Add(-time.Hour), Expires: now.Add(time.Hour)},
+	}
+	data, _ := json.Marshal(stored)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewList(path)
+	if err != nil {
+		t.Fatalf("NewList() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		identity login.IdentityData
+		addr     net.Addr
+		want     string
+		banned   bool
+	}{
+		{name: "permanent ban", identity: login.IdentityData{DisplayName: "steve"}, banned: true, want: "Steve"},
+		{name: "expired ban", identity: login.IdentityData{DisplayName: "Alex"}, banned: false},
+		{name: "active ip ban", identity: login.IdentityData{DisplayName: "Notch"}, addr: &net.UDPAddr{IP: net.ParseIP("10.1.2.3")}, banned: true, want: "10.0.0.0/8"},
+		{name: "other ip", identity: login.IdentityData{DisplayName: "Notch"}, addr: &net.UDPAddr{IP: net.ParseIP("192.168.1.1")}, banned: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, banned := l.Find(test.identity, test.addr)
+			if banned != test.banned || b.Target != test.want {
+				t.Errorf("Find() = %q, %v, want %q, %v", b.Target, banned, test.want, test.banned)
+			}
+		})
+	}
+
+	if bans := l.Bans(); len(bans) != 2 {
+		t.Errorf("Bans() returned %d bans, want 2: %v", len(bans), bans)
+	}
+
+	// Adding a ban saves the list, which drops expired bans from the file.
+	if err := l.Add(Ban{Kind: KindXUID, Target: "2535400000000001", Expires: now.Add(-time.Second)}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []Ban
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved bans are invalid: %v", err)
+	}
+	if len(saved) != 2 || saved[0].Target != "Steve" || saved[1].Target != "10.0.0.0/8" {
+		t.Errorf("saved bans = %v, want the bans of Steve and 10.0.0.0/8", saved)
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chzyer/readline"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/ban"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// console is an interactive console read from stdin, which allows the proxy to be managed from the terminal.
+type console struct {
+	p        *portal.Portal
+	reloader *portal.ConfigReloader
+	stop     func()
+
+	rl *readline.Instance
+}
+
+// command is a command that may be run in the console.
+type command struct {
+	usage       string
+	description string
+	run         func(c *console, args []string) error
+}
+
+// commands holds all commands of the console, keyed by their name.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":        {"help", "Shows all commands", (*console).help},
+		"list":        {"list <players|servers>", "Lists the online players or the registered servers", (*console).list},
+		"find":        {"find <player>", "Shows the server a player is connected to", (*console).find},
+		"transfer":    {"transfer <player> <server>", "Transfers a player to a server", (*console).transfer},
+		"kick":        {"kick <player> [reason]", "Disconnects a player from the proxy", (*console).kick},
+		"broadcast":   {"broadcast <message>", "Sends a message to all online players", (*console).broadcast},
+		"whitelist":   {"whitelist <on|off|list|add|remove> [player] [xuid]", "Manages the whitelist", (*console).whitelist},
+		"ban":         {"ban <name|uuid|xuid|ip> <target> [duration, e.g. 12h or 7d] [reason]", "Bans a player or IP address", (*console).ban},
+		"unban":       {"unban <name|uuid|xuid|ip> <target>", "Removes a ban", (*console).unban},
+		"bans":        {"bans", "Lists all bans", (*console).bans},
+		"maintenance": {"maintenance <on|off> [kick]", "Toggles maintenance mode", (*console).maintenance},
+		"reload":      {"reload", "Reloads the configuration", (*console).reload},
+		"stop":        {"stop", "Shuts down the proxy", (*console).shutdown},
+	}
+}
+
+// newConsole creates a console for the proxy passed. The function stop is called when the stop command is run.
+func newConsole(p *portal.Portal, reloader *portal.ConfigReloader, stop func()) (*console, error) {
+	c := &console{p: p, reloader: reloader, stop: stop}
+	players := readline.PcItemDynamic(c.playerNames)
+	servers := readline.PcItemDynamic(c.serverNames)
+	kinds := func(next ...readline.PrefixCompleterInterface) []readline.PrefixCompleterInterface {
+		return []readline.PrefixCompleterInterface{
+			readline.PcItem("name", next...), readline.PcItem("uuid"), readline.PcItem("xuid"), readline.PcItem("ip"),
+		}
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "> ",
+		InterruptPrompt: "^C",
+		AutoComplete: readline.NewPrefixCompleter(
+			readline.PcItem("help"),
+			readline.PcItem("list", readline.PcItem("players"), readline.PcItem("servers")),
+			readline.PcItem("find", players),
+			readline.PcItem("transfer", readline.PcItemDynamic(c.playerNames, servers)),
+			readline.PcItem("kick", players),
+			readline.PcItem("broadcast"),
+			readline.PcItem("whitelist",
+				readline.PcItem("on"), readline.PcItem("off"), readline.PcItem("list"),
+				readline.PcItem("add", players), readline.PcItem("remove", players),
+			),
+			readline.PcItem("ban", kinds(players)...),
+			readline.PcItem("unban", kinds()...),
+			readline.PcItem("bans"),
+			readline.PcItem("maintenance", readline.PcItem("on", readline.PcItem("kick")), readline.PcItem("off")),
+			readline.PcItem("reload"),
+			readline.PcItem("stop"),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.rl = rl
+	return c, nil
+}
+
+// Run reads commands from the console and runs them until stdin is closed or the console is closed.
+func (c *console) Run() {
+	for {
+		line, err := c.rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		} else if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		cmd, ok := commands[strings.ToLower(args[0])]
+		if !ok {
+			c.printf("Unknown command %q, run \"help\" for a list of commands.", args[0])
+			continue
+		}
+		if err := cmd.run(c, args[1:]); err != nil {
+			c.printf("%v", err)
+		}
+	}
+}
+
+// Close closes the console and restores the terminal.
+func (c *console) Close() error {
+	return c.rl.Close()
+}
+
+// printf prints a line to the console.
+func (c *console) printf(format string, a ...any) {
+	_, _ = fmt.Fprintf(c.rl.Stdout(), format+"\n", a...)
+}
+
+// usage returns an error with the usage of the command passed.
+func usage(name string) error {
+	return fmt.Errorf("usage: %s", commands[name].usage)
+}
+
+// help prints all the commands of the console.
+func (c *console) help([]string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.printf("%-55s %s", commands[name].usage, commands[name].description)
+	}
+	return nil
+}
+
+// list lists the online players or the registered servers.
+func (c *console) list(args []string) error {
+	if len(args) != 1 {
+		return usage("list")
+	}
+	switch strings.ToLower(args[0]) {
+	case "players", "sessions":
+		sessions := c.p.SessionStore().All()
+		c.printf("There are %d players online:", len(sessions))
+		for _, s := range sessions {
+			c.printf("- %s on %s (%dms)", s.Conn().IdentityData().DisplayName, serverName(s), s.Conn().Latency().Milliseconds())
+		}
+	case "servers":
+		servers := c.p.ServerRegistry().Servers()
+		sort.Slice(servers, func(i, j int) bool { return servers[i].Name() < servers[j].Name() })
+		c.printf("There are %d servers registered:", len(servers))
+		for _, srv := range servers {
+			health := "healthy"
+			if !srv.Healthy() {
+				health = "unhealthy"
+			}
+			c.printf("- %s (group %q, %s): %d players, %s, %dms", srv.Name(), srv.Group(), srv.Address(), srv.PlayerCount(), health, srv.Latency().Milliseconds())
+		}
+	default:
+		return usage("list")
+	}
+	return nil
+}
+
+// find shows the server a player is connected to.
+func (c *console) find(args []string) error {
+	if len(args) != 1 {
+		return usage("find")
+	}
+	s, err := c.session(args[0])
+	if err != nil {
+		return err
+	}
+	identity := s.Conn().IdentityData()
+	c.printf("%s (UUID %s, XUID %s) is on %s from %s", identity.DisplayName, identity.Identity, identity.XUID, serverName(s), s.Conn().RemoteAddr())
+	return nil
+}
+
+// transfer transfers a player to a server.
+func (c *console) transfer(args []string) error {
+	if len(args) != 2 {
+		return usage("transfer")
+	}
+	s, err := c.session(args[0])
+	if err != nil {
+		return err
+	}
+	srv, ok := c.p.ServerRegistry().Server(args[1])
+	if !ok {
+		return fmt.Errorf("server %s not found", args[1])
+	}
+	c.printf("Transferring %s to %s...", s.Conn().IdentityData().DisplayName, srv.Name())
+	go func() {
+		if err := s.Transfer(srv); err != nil {
+			c.printf("Failed to transfer %s to %s: %v", s.Conn().IdentityData().DisplayName, srv.Name(), err)
+			return
+		}
+		c.printf("Transferred %s to %s.", s.Conn().IdentityData().DisplayName, srv.Name())
+	}()
+	return nil
+}
+
+// kick disconnects a player from the proxy.
+func (c *console) kick(args []string) error {
+	if len(args) < 1 {
+		return usage("kick")
+	}
+	s, err := c.session(args[0])
+	if err != nil {
+		return err
+	}
+	reason := strings.Join(args[1:], " ")
+	if reason == "" {
+		reason = "Kicked by an operator"
+	}
+	s.Disconnect(text.Colourf("<red>%s</red>", reason))
+	c.printf("Kicked %s.", s.Conn().IdentityData().DisplayName)
+	return nil
+}
+
+// broadcast sends a message to all online players.
+func (c *console) broadcast(args []string) error {
+	if len(args) == 0 {
+		return usage("broadcast")
+	}
+	message := strings.Join(args, " ")
+	for _, s := range c.p.SessionStore().All() {
+		_ = s.Conn().WritePacket(&packet.Text{TextType: packet.TextTypeRaw, Message: message})
+	}
+	c.printf("Broadcast: %s", message)
+	return nil
+}
+
+// whitelist manages the whitelist of the proxy.
+func (c *console) whitelist(args []string) error {
+	if len(args) == 0 {
+		return usage("whitelist")
+	}
+	switch strings.ToLower(args[0]) {
+	case "on", "off":
+		if err := c.p.SetWhitelistEnabled(strings.EqualFold(args[0], "on")); err != nil {
+			return err
+		}
+		c.printf("The whitelist has been turned %s.", strings.ToLower(args[0]))
+		return nil
+	}
+
+	w, ok := c.p.Whitelist().(*session.PersistentWhitelist)
+	if !ok {
+		return fmt.Errorf("whitelist %T cannot be changed at runtime", c.p.Whitelist())
+	}
+	switch strings.ToLower(args[0]) {
+	case "list":
+		players := w.Players()
+		c.printf("There are %d whitelisted players (enabled: %v):", len(players), w.Enabled())
+		for _, e := range players {
+			c.printf("- %s %s", e.Name, e.XUID)
+		}
+	case "add":
+		if len(args) < 2 || len(args) > 3 {
+			return usage("whitelist")
+		}
+		var xuid string
+		if len(args) == 3 {
+			xuid = args[2]
+		} else if s, err := c.session(args[1]); err == nil {
+			xuid = s.Conn().IdentityData().XUID
+		}
+		if err := w.Add(args[1], xuid); err != nil {
+			return err
+		}
+		c.printf("Added %s to the whitelist.", args[1])
+	case "remove":
+		if len(args) != 2 {
+			return usage("whitelist")
+		}
+		ok, err := w.Remove(args[1])
+		if err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%s is not whitelisted", args[1])
+		}
+		c.printf("Removed %s from the whitelist.", args[1])
+		if w.Enabled() {
+			c.p.EnforceWhitelist()
+		}
+	default:
+		return usage("whitelist")
+	}
+	return nil
+}
+
+// ban bans a player or IP address.
+func (c *console) ban(args []string) error {
+	if len(args) < 2 {
+		return usage("ban")
+	}
+	b := ban.Ban{Kind: ban.Kind(strings.ToLower(args[0])), Target: args[1], Issuer: "Console"}
+	args = args[2:]
+	if len(args) > 0 && looksLikeDuration(args[0]) {
+		d, err := parseDuration(args[0])
+		if err != nil || d <= 0 {
+			return usage("ban")
+		}
+		b.Expires, args = time.Now().Add(d), args[1:]
+	}
+	b.Reason = strings.Join(args, " ")
+	if err := c.p.Ban(b); err != nil {
+		return err
+	}
+	c.printf("Banned %s %s.", b.Kind, b.Target)
+	return nil
+}
+
+// looksLikeDuration returns if the argument passed looks like a duration rather than the first word of a reason,
+// which is the case if it starts with a number.
+func looksLikeDuration(s string) bool {
+	s = strings.TrimLeft(s, "+-.")
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// parseDuration parses a duration in the format of time.ParseDuration, such as "30m" or "1h30m". Unlike
+// time.ParseDuration, a number of days may be passed at the start using the "d" unit, such as "7d" or "1d12h".
+func parseDuration(s string) (time.Duration, error) {
+	var days time.Duration
+	if before, after, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(before)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days, s = time.Duration(n)*time.Hour*24, after
+		if s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	return days + d, err
+}
+
+// unban removes a ban.
+func (c *console) unban(args []string) error {
+	if len(args) != 2 {
+		return usage("unban")
+	}
+	ok, err := c.p.Unban(ban.Kind(strings.ToLower(args[0])), args[1])
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%s %s is not banned", args[0], args[1])
+	}
+	c.printf("Unbanned %s %s.", args[0], args[1])
+	return nil
+}
+
+// bans lists all bans.
+func (c *console) bans([]string) error {
+	bans := c.p.Bans().Bans()
+	c.printf("There are %d bans:", len(bans))
+	for _, b := range bans {
+		expires := "never"
+		if !b.Expires.IsZero() {
+			expires = b.Expires.Format(time.RFC1123)
+		}
+		c.printf("- %s %s by %s, expires %s: %s", b.Kind, b.Target, b.Issuer, expires, b.Reason)
+	}
+	return nil
+}
+
+// maintenance toggles maintenance mode.
+func (c *console) maintenance(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return usage("maintenance")
+	}
+	enabled, kick := strings.EqualFold(args[0], "on"), len(args) == 2 && strings.EqualFold(args[1], "kick")
+	if !enabled && !strings.EqualFold(args[0], "off") {
+		return usage("maintenance")
+	}
+	return c.p.SetMaintenance(enabled, kick)
+}
+
+// reload reloads the configuration.
+func (c *console) reload([]string) error {
+	restart, err := c.reloader.Reload()
+	if err != nil {
+		return err
+	}
+	if len(restart) > 0 {
+		c.printf("Reloaded the configuration. These settings require a restart: %s", strings.Join(restart, ", "))
+		return nil
+	}
+	c.printf("Reloaded the configuration.")
+	return nil
+}
+
+// shutdown shuts down the proxy.
+func (c *console) shutdown([]string) error {
+	c.printf("Shutting down...")
+	c.stop()
+	return nil
+}
+
+// session finds the session of the online player with the name passed.
+func (c *console) session(name string) (*session.Session, error) {
+	if s, ok := c.p.SessionStore().LoadFromName(name); ok {
+		return s, nil
+	}
+	for _, s := range c.p.SessionStore().All() {
+		if strings.EqualFold(s.Conn().IdentityData().DisplayName, name) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("player %s is not online", name)
+}
+
+// playerNames returns the names of all online players, used for tab completion.
+func (c *console) playerNames(string) []string {
+	sessions := c.p.SessionStore().All()
+	names := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		names = append(names, s.Conn().IdentityData().DisplayName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serverNames returns the names of all registered servers, used for tab completion.
+func (c *console) serverNames(string) []string {
+	servers := c.p.ServerRegistry().Servers()
+	names := make([]string, 0, len(servers))
+	for _, srv := range servers {
+		names = append(names, srv.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serverName returns the name of the server the session is connected to.
+func serverName(s *session.Session) string {
+	if srv := s.Server(); srv != nil {
+		return srv.Name()
+	}
+	return "no server"
+}
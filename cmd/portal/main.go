@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/api"
+	"github.com/paroxity/portal/ban"
+	"github.com/paroxity/portal/dashboard"
+	"github.com/paroxity/portal/health"
+	portallog "github.com/paroxity/portal/log"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/metrics"
+	"github.com/paroxity/portal/queue"
+	"github.com/paroxity/portal/session"
+	"github.com/paroxity/portal/socket"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the configuration file, which may be JSON, YAML or TOML (default \"config.json\" in the data directory)")
+	dataDir := flag.String("data", ".", "directory in which relative paths in the configuration, such as logs, bans and the whitelist, are stored")
+	logLevel := flag.String("log-level", "", "log level that overrides the level in the configuration")
+	noConsole := flag.Bool("no-console", false, "disables the interactive console read from stdin")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		ForceColors:     true,
+		FullTimestamp:   true,
+		TimestampFormat: "15:04:05",
+	})
+	log := logging.NewLogrus(logger)
+
+	if *logLevel != "" {
+		if _, err := logrus.ParseLevel(*logLevel); err != nil {
+			logger.Fatalf("invalid log level '%s': %v", *logLevel, err)
+		}
+	}
+	path, err := dataDirectory(*configPath, *dataDir)
+	if err != nil {
+		logger.Fatalf("unable to use data directory %s: %v", *dataDir, err)
+	}
+	conf := readConfig(log, path, *dataDir)
+	overrideConfig := func(c *portal.Config) {
+		if *logLevel != "" {
+			c.Logger.Level = *logLevel
+		}
+	}
+	overrideConfig(&conf)
+	var output io.Writer = os.Stderr
+	if conf.Logger.File != "" {
+		fileLogger, err := portallog.New(conf.Logger.File)
+		if err != nil {
+			logger.Fatalf("unable to create file logger: %v", err)
+		}
+		output = fileLogger
+	}
+	var logs *dashboard.LogBuffer
+	if conf.API.Enabled && conf.API.Dashboard {
+		logs = dashboard.NewLogBuffer(500)
+		output = io.MultiWriter(output, logs)
+	}
+	logger.SetOutput(output)
+	level, err := logrus.ParseLevel(conf.Logger.Level)
+	if err != nil {
+		logger.Errorf("unable to parse log level '%s': %v", conf.Logger.Level, err)
+		level = logrus.InfoLevel
+	}
+	logger.SetLevel(level)
+
+	resourcePacks, err := portal.LoadResourcePacks(conf.ResourcePacks.Directory)
+	if err != nil {
+		logger.Fatalf("unable to load resource packs: %v", err)
+	}
+	for i, pack := range resourcePacks {
+		key, ok := conf.ResourcePacks.EncryptionKeys[pack.UUID()]
+		if ok {
+			resourcePacks[i] = pack.WithContentKey(key)
+		}
+	}
+
+	var healthCheckInterval time.Duration
+	if conf.HealthCheck.Enabled {
+		healthCheckInterval = time.Second * time.Duration(conf.HealthCheck.Interval)
+	}
+
+	bans, err := ban.NewList(conf.Bans.File)
+	if err != nil {
+		logger.Fatalf("unable to load bans: %v", err)
+	}
+
+	whitelist, err := session.NewPersistentWhitelist(conf.Whitelist.File, conf.Whitelist.Enabled, conf.Whitelist.Players, conf.Whitelist.Message)
+	if err != nil {
+		logger.Fatalf("unable to load whitelist: %v", err)
+	}
+
+	maintenance, err := portal.NewMaintenance(conf.Maintenance.File, conf.Maintenance.Message, conf.Maintenance.MOTD, conf.Maintenance.Bypass)
+	if err != nil {
+		logger.Fatalf("unable to load maintenance state: %v", err)
+	}
+
+	p := portal.New(portal.Options{
+		Logger: log,
+
+		Address: conf.Network.Address,
+		Servers: conf.StaticServers(),
+		ListenConfig: minecraft.ListenConfig{
+			StatusProvider: portal.NewMOTDStatusProvider(conf.Network.MOTD),
+
+			ResourcePacks:        resourcePacks,
+			TexturePacksRequired: conf.ResourcePacks.Required,
+		},
+
+		HealthCheckInterval:  healthCheckInterval,
+		HealthCheckTimeout:   time.Second * time.Duration(conf.HealthCheck.Timeout),
+		HealthCheckThreshold: conf.HealthCheck.UnhealthyThreshold,
+
+		CapacityBypass: conf.Capacity.Bypass,
+
+		FallbackServer:  conf.Fallback.Server,
+		DisableFallback: !conf.Fallback.Enabled,
+
+		Maintenance: maintenance,
+		Bans:        bans,
+		Whitelist:   whitelist,
+
+		ShutdownMessage:         conf.Shutdown.Message,
+		ShutdownFallbackAddress: conf.Shutdown.FallbackAddress,
+	})
+	loadBalancer, err := conf.NewLoadBalancer(p.ServerRegistry(), log)
+	if err != nil {
+		logger.Fatalf("unable to create load balancer: %v", err)
+	}
+	p.SetLoadBalancer(loadBalancer)
+
+	socketServer := socket.NewDefaultServer(conf.Network.Communication.Address, conf.Network.Communication.Secret, p.SessionStore(), p.ServerRegistry(), log, conf.Network.ReaderLimits)
+	socketServer.SetLoadBalancer(p.LoadBalancer())
+	if err := socketServer.Listen(); err != nil {
+		p.Logger().Fatalf("socket server failed to listen: %v", err)
+	}
+	p.SetSocketServer(socketServer)
+
+	// The queue is set before the proxy starts listening, so that players joining while every server is full are
+	// queued from the start.
+	if conf.Queue.Enabled {
+		q, err := queue.New(p.SessionStore(), p.ServerRegistry(), p.LoadBalancer(), conf.Queue.ServerAddress, conf.Queue.Priorities, log)
+		if err != nil {
+			logger.Fatalf("unable to create queue: %v", err)
+		}
+		p.SetQueue(q)
+		socketServer.SetQueue(q)
+		go q.Run(time.Second)
+	}
+
+	// The HTTP servers are started before the proxy starts listening, so that the readiness probe reports that the
+	// proxy is not ready until it is.
+	var healthServer *http.Server
+	if conf.Health.Enabled {
+		healthServer = serveHTTP(p, "health", conf.Health.Address, health.New(p))
+	}
+	var metricsServer *http.Server
+	if conf.Metrics.Enabled {
+		// The metrics are created before the proxy starts listening, so that every session is measured.
+		mux := http.NewServeMux()
+		mux.Handle(conf.Metrics.Path, metrics.New(p).Handler())
+		metricsServer = serveHTTP(p, "metrics", conf.Metrics.Address, mux)
+	}
+
+	reloader := portal.NewConfigReloader(p, path, *dataDir, conf)
+	reloader.Override(overrideConfig)
+
+	var apiServer *http.Server
+	if conf.API.Enabled {
+		apiHandler := api.New(p, conf.API.Token)
+		apiHandler.SetReloader(reloader)
+		mux := http.NewServeMux()
+		mux.Handle("/api/", apiHandler)
+		if conf.API.Dashboard {
+			mux.Handle("/", dashboard.New(p, apiHandler, logs))
+		}
+		apiServer = serveHTTP(p, "admin API", conf.API.Address, mux)
+	}
+
+	if err := p.Listen(); err != nil {
+		logger.Fatalf("failed to listen on %s: %v", conf.Network.Address, err)
+	}
+
+	if conf.PlayerLatency.Report {
+		go socketServer.ReportPlayerLatency(time.Second * time.Duration(conf.PlayerLatency.UpdateInterval))
+	}
+
+	go reloader.Watch(time.Second * 5)
+
+	stop, stopOnce := make(chan struct{}), sync.Once{}
+	if !*noConsole {
+		c, err := newConsole(p, reloader, func() { stopOnce.Do(func() { close(stop) }) })
+		if err != nil {
+			logger.Fatalf("unable to start console: %v", err)
+		}
+		defer c.Close()
+		go c.Run()
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	signals:
+		for {
+			select {
+			case sig := <-c:
+				if sig != syscall.SIGHUP {
+					break signals
+				}
+				p.Logger().Infof("reloading configuration...")
+				if _, err := reloader.Reload(); err != nil {
+					p.Logger().Errorf("failed to reload configuration: %v", err)
+				}
+			case <-stop:
+				break signals
+			}
+		}
+		_ = reloader.Close()
+		go func() {
+			// A second interrupt while shutting down stops the proxy immediately.
+			for sig := range c {
+				if sig != syscall.SIGHUP {
+					p.Logger().Fatalf("forcing shutdown")
+				}
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(conf.Shutdown.Timeout))
+		defer cancel()
+		if err := p.Shutdown(ctx); err != nil {
+			p.Logger().Errorf("failed to shut down gracefully: %v", err)
+		}
+		for _, srv := range []*http.Server{healthServer, metricsServer, apiServer} {
+			if srv != nil {
+				_ = srv.Close()
+			}
+		}
+	}()
+
+	for {
+		s, err := p.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			break
+		} else if err != nil {
+			if s != nil {
+				s.Disconnect(text.Colourf("<red>%v</red>", err))
+			}
+			p.Logger().Infof("player could not join: %v", err)
+		}
+	}
+	<-shutdown
+	p.Logger().Infof("proxy has been shut down")
+}
+
+// serveHTTP starts serving the handler passed over HTTP on the address passed. The proxy is stopped if the address
+// cannot be listened on.
+func serveHTTP(p *portal.Portal, name, address string, handler http.Handler) *http.Server {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		p.Logger().Fatalf("%s server failed to listen on %s: %v", name, address, err)
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: time.Second * 10}
+	go func() {
+		if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			p.Logger().Errorf("%s server stopped: %v", name, err)
+		}
+	}()
+	p.Logger().Infof("%s server listening on %s", name, l.Addr())
+	return srv
+}
+
+// dataDirectory creates the data directory passed if it does not exist yet, and returns the path of the
+// configuration file. If no path is passed, the configuration file is stored as config.json in the data directory.
+func dataDirectory(configPath, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if configPath == "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	return configPath, nil
+}
+
+// readConfig loads the configuration file at the path passed, resolving relative paths in it against the data
+// directory passed. If it does not exist yet, the default configuration is written to it first.
+func readConfig(logger logging.Logger, path, dataDir string) portal.Config {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := portal.SaveConfig(path, portal.DefaultConfig()); err != nil {
+			logger.Fatalf("error writing default config: %v", err)
+		}
+	}
+	c, err := portal.LoadConfig(path, dataDir, logger)
+	if err != nil {
+		logger.Fatalf("error reading config: %v", err)
+	}
+	return c
+}
@@ -1,9 +1,14 @@
 package portal
 
 import (
+	"fmt"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
 	"github.com/sandertv/gophertunnel/minecraft/resource"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config represents the base configuration for portal. It holds settings that affect different aspects of the
@@ -14,6 +19,8 @@ type Config struct {
 		// Address is the address on which the proxy should listen. Players may connect to this address in
 		// order to join. It should be in the format of "ip:port".
 		Address string `json:"address"`
+		// MOTD is the message shown for the proxy in the server list.
+		MOTD string `json:"motd"`
 		// Communication holds settings related to the communication aspects of the proxy.
 		Communication struct {
 			// Address is the address on which the communication service should listen. External connections
@@ -44,12 +51,82 @@ type Config struct {
 		// UpdateInterval is the interval to report a player's ping if Report is true.
 		UpdateInterval int `json:"update_interval"`
 	} `json:"player_latency"`
+	// Servers is a list of servers that are registered when the proxy starts. These servers are never removed,
+	// which allows servers that cannot communicate with the proxy over the socket to be used.
+	Servers []StaticServer `json:"servers"`
+	// Capacity holds settings related to servers that have reached their maximum amount of players.
+	Capacity struct {
+		// Bypass is a list of player names or XUIDs of players that may join servers that are full.
+		Bypass []string `json:"bypass"`
+	} `json:"capacity"`
+	// LoadBalancer holds settings related to the way players are split across servers.
+	LoadBalancer struct {
+		// Strategy is the strategy used to pick the server a player joins. It can be one of "split",
+		// "round_robin", "weighted", "random", "least_connections" or "sticky".
+		Strategy string `json:"strategy"`
+		// StickyFile is the path to the file in which the last server of each player is stored, used by the
+		// sticky strategy.
+		StickyFile string `json:"sticky_file"`
+	} `json:"load_balancer"`
+	// HealthCheck holds settings related to checking if the servers on the proxy are still online.
+	HealthCheck struct {
+		// Enabled is if servers should be pinged at a regular interval to check if they are still online.
+		Enabled bool `json:"enabled"`
+		// Interval is the interval in seconds at which servers are pinged.
+		Interval int `json:"interval"`
+		// Timeout is the amount of seconds a server has to respond to a ping.
+		Timeout int `json:"timeout"`
+		// UnhealthyThreshold is the amount of pings a server must fail in a row to be marked as unhealthy. Unhealthy
+		// servers are excluded from load balancing and transfers until they respond again.
+		UnhealthyThreshold int `json:"unhealthy_threshold"`
+	} `json:"health_check"`
+	// Queue holds settings related to queueing players when every server, or the server they want to join, is full.
+	Queue struct {
+		// Enabled is if players should be queued instead of being refused when servers are full.
+		Enabled bool `json:"enabled"`
+		// ServerAddress is the address of the server queued players wait on. If empty, queued players wait in an
+		// empty world hosted by the proxy. It should be in the format of "ip:port".
+		ServerAddress string `json:"server_address"`
+		// Priorities is a map of player names or XUIDs to their priority tier. Players with a higher tier are moved
+		// out of the queue first. Players without a tier have a tier of 0.
+		Priorities map[string]int `json:"priorities,omitempty"`
+	} `json:"queue"`
+	// Fallback holds settings related to moving players to another server when their server closes the connection.
+	Fallback struct {
+		// Enabled is if players should be moved to another server instead of being disconnected from the proxy.
+		Enabled bool `json:"enabled"`
+		// Server is the name of the server players should be moved to. If empty or unavailable, the load balancer
+		// is used to find another server.
+		Server string `json:"server"`
+	} `json:"fallback"`
+	// Maintenance holds settings related to the maintenance mode of the proxy.
+	Maintenance struct {
+		// File is the path to the file in which the state of maintenance mode is stored.
+		File string `json:"file"`
+		// Message is the message shown to players that are refused while maintenance mode is enabled.
+		Message string `json:"message"`
+		// MOTD is the MOTD shown in the server list while maintenance mode is enabled.
+		MOTD string `json:"motd"`
+		// Bypass is a list of player names or XUIDs of players that may join while maintenance mode is enabled.
+		Bypass []string `json:"bypass"`
+	} `json:"maintenance"`
+	// Bans holds settings related to banning players from the proxy.
+	Bans struct {
+		// File is the path to the file in which the bans are stored.
+		File string `json:"file"`
+	} `json:"bans"`
 	// Whitelist holds settings related to the proxy whitelist.
 	Whitelist struct {
 		// Enabled is if the whitelist is enabled.
 		Enabled bool `json:"enabled"`
-		// Players is a list of whitelisted players' usernames.
+		// Players is a list of whitelisted players' usernames. It is only used to create the whitelist file when it
+		// does not exist yet.
 		Players []string `json:"players"`
+		// File is the path to the file in which the whitelist is stored. Once it exists, the enabled status and
+		// players in the file are used instead of the ones above.
+		File string `json:"file"`
+		// Message is the message shown to players that are not whitelisted.
+		Message string `json:"message"`
 	} `json:"whitelist"`
 	// ResourcePacks holds settings related to sending resource packs to players.
 	ResourcePacks struct {
@@ -60,21 +137,157 @@ type Config struct {
 		// EncryptionKeys is a map of resource pack UUIDs to their encryption key.
 		EncryptionKeys map[string]string `json:"encryption_keys,omitempty"`
 	} `json:"resource_packs"`
+	// Metrics holds settings related to exporting metrics of the proxy in the Prometheus format.
+	Metrics struct {
+		// Enabled is if the metrics should be served over HTTP.
+		Enabled bool `json:"enabled"`
+		// Address is the address on which the metrics are served. It should be in the format of "ip:port".
+		Address string `json:"address"`
+		// Path is the HTTP path on which the metrics are served.
+		Path string `json:"path"`
+	} `json:"metrics"`
+	// API holds settings related to the HTTP admin API of the proxy.
+	API struct {
+		// Enabled is if the admin API should be served.
+		Enabled bool `json:"enabled"`
+		// Address is the address on which the admin API is served. It should be in the format of "ip:port".
+		Address string `json:"address"`
+		// Token is the token requests to the admin API must carry in the Authorization header, in the format of
+		// "Bearer <token>". It must not be empty if the admin API is enabled.
+		Token string `json:"token"`
+		// Dashboard is if a web dashboard should be served on the same address as the admin API. Moderators log in
+		// to the dashboard using the token above.
+		Dashboard bool `json:"dashboard"`
+	} `json:"api"`
+	// Health holds settings related to the health and readiness probes of the proxy.
+	Health struct {
+		// Enabled is if the /healthz and /readyz endpoints should be served over HTTP.
+		Enabled bool `json:"enabled"`
+		// Address is the address on which the endpoints are served. It should be in the format of "ip:port".
+		Address string `json:"address"`
+	} `json:"health"`
+	// Shutdown holds settings related to shutting down the proxy.
+	Shutdown struct {
+		// Message is the message shown to players when they are disconnected because the proxy is shutting down.
+		Message string `json:"message"`
+		// FallbackAddress is the address players are transferred to when the proxy is shutting down. If empty,
+		// players are disconnected with the message above instead. It should be in the format of "ip:port".
+		FallbackAddress string `json:"fallback_address"`
+		// Timeout is the amount of seconds the proxy waits for all sessions to be drained before shutting down. It
+		// must be positive.
+		Timeout int `json:"timeout"`
+	} `json:"shutdown"`
+}
+
+// StaticServer represents a server declared in the configuration of the proxy.
+type StaticServer struct {
+	// Name is the name of the server. It must be unique across all servers on the proxy.
+	Name string `json:"name"`
+	// Address is the address of the server. It should be in the format of "ip:port".
+	Address string `json:"address"`
+	// Group is the name of the group the server is in. It may be empty.
+	Group string `json:"group"`
+	// Weight is the weight of the server used by the weighted load balancing strategy. If zero, a weight of 1 is
+	// used.
+	Weight int `json:"weight,omitempty"`
+	// MaxPlayers is the maximum amount of players that may be connected to the server. If zero, the amount of
+	// players is not limited.
+	MaxPlayers int `json:"max_players,omitempty"`
 }
 
 // DefaultConfig returns a configuration with the default values filled out.
 func DefaultConfig() (c Config) {
 	c.Network.Address = ":19132"
+	c.Network.MOTD = "Portal"
 	c.Network.Communication.Address = ":19131"
 	c.Network.ReaderLimits = true
 	c.Logger.File = "proxy.log"
 	c.Logger.Level = "debug"
 	c.PlayerLatency.Report = true
 	c.PlayerLatency.UpdateInterval = 5
+	c.Servers = []StaticServer{}
+	c.Capacity.Bypass = []string{}
+	c.LoadBalancer.Strategy = "split"
+	c.LoadBalancer.StickyFile = "sticky_servers.json"
+	c.HealthCheck.Enabled = true
+	c.HealthCheck.Interval = 5
+	c.HealthCheck.Timeout = 2
+	c.HealthCheck.UnhealthyThreshold = 3
+	c.Fallback.Enabled = true
+	c.Maintenance.File = "maintenance.json"
+	c.Maintenance.Message = "The network is under maintenance"
+	c.Maintenance.MOTD = "Maintenance"
+	c.Maintenance.Bypass = []string{}
+	c.Bans.File = "bans.json"
+	c.Whitelist.Players = []string{}
+	c.Whitelist.File = "whitelist.json"
+	c.Whitelist.Message = "Server is whitelisted"
 	c.ResourcePacks.Directory = "resource_packs"
+	c.Metrics.Address = ":9191"
+	c.Metrics.Path = "/metrics"
+	c.API.Address = "127.0.0.1:19180"
+	c.Health.Address = ":19181"
+	c.Shutdown.Message = "Proxy is shutting down"
+	c.Shutdown.Timeout = 10
 	return
 }
 
+// StaticServers creates the static servers declared in the configuration so that they can be registered on the
+// proxy.
+func (c Config) StaticServers() []*server.Server {
+	servers := make([]*server.Server, 0, len(c.Servers))
+	for _, s := range c.Servers {
+		srv := server.NewStatic(s.Name, s.Group, s.Address)
+		if s.Weight != 0 {
+			srv.SetWeight(s.Weight)
+		}
+		srv.SetMaxPlayers(s.MaxPlayers)
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+// resolvePaths joins the relative paths of files and directories in the configuration with the directory passed. If
+// the directory is empty, the paths are left unchanged.
+func (c *Config) resolvePaths(dir string) {
+	if dir == "" {
+		return
+	}
+	for _, path := range []*string{
+		&c.Logger.File,
+		&c.LoadBalancer.StickyFile,
+		&c.Maintenance.File,
+		&c.Bans.File,
+		&c.Whitelist.File,
+		&c.ResourcePacks.Directory,
+	} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+// NewLoadBalancer creates a load balancer for the strategy set in the configuration, which balances players across
+// the servers in the registry passed. Errors that occur while the load balancer is used are logged to the logger
+// passed. An error is returned if the strategy is unknown.
+func (c Config) NewLoadBalancer(registry *server.Registry, log logging.Logger) (session.LoadBalancer, error) {
+	switch strings.ToLower(c.LoadBalancer.Strategy) {
+	case "", "split":
+		return session.NewSplitLoadBalancer(registry), nil
+	case "round_robin":
+		return session.NewRoundRobinLoadBalancer(registry), nil
+	case "weighted":
+		return session.NewWeightedLoadBalancer(registry), nil
+	case "random":
+		return session.NewRandomLoadBalancer(registry), nil
+	case "least_connections":
+		return session.NewLeastConnectionsLoadBalancer(registry), nil
+	case "sticky":
+		return session.NewStickyLoadBalancer(registry, c.LoadBalancer.StickyFile, session.NewSplitLoadBalancer(registry), log)
+	}
+	return nil, fmt.Errorf("unknown load balancing strategy %q", c.LoadBalancer.Strategy)
+}
+
 // LoadResourcePacks attempts to load all the resource packs in the provided directory. If the directory does not exist,
 // it will be created. If any pack fails to compile, the error will be returned.
 func LoadResourcePacks(dir string) ([]*resource.Pack, error) {
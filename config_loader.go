@@ -0,0 +1,238 @@
+package portal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/paroxity/portal/logging"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables that override settings of the configuration loaded using
+// LoadConfig. The rest of the name of a variable is the path of the setting in upper case, with sections separated
+// by underscores, such as PORTAL_NETWORK_COMMUNICATION_SECRET.
+const EnvPrefix = "PORTAL_"
+
+// LoadConfig loads the configuration file at the path passed. The format of the file is picked using its
+// extension, which may be .json, .yaml, .yml or .toml. Settings that are missing from the file keep their default
+// value, and settings can be overridden using environment variables prefixed with EnvPrefix. Unknown settings and
+// invalid values are reported together in the error returned. Environment variables prefixed with EnvPrefix that do
+// not match a setting are logged as a warning to the logger passed. Relative paths of files and directories in the
+// configuration are resolved against the directory dir, or the working directory if dir is empty.
+func LoadConfig(path, dir string, log logging.Logger) (Config, error) {
+	c := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	unknown, err := decodeConfig(data, filepath.Ext(path), &c)
+	if err != nil {
+		return c, fmt.Errorf("decode config %s: %w", path, err)
+	}
+	unknownEnv, err := applyEnv(&c, os.Environ())
+	for _, key := range unknownEnv {
+		logging.Warnf(log, "ignoring environment variable %s: unknown setting", key)
+	}
+	c.resolvePaths(dir)
+	return c, errors.Join(unknown, err, c.Validate())
+}
+
+// SaveConfig saves the configuration passed to the file at the path passed. The format of the file is picked using
+// its extension, in the same way as LoadConfig.
+func SaveConfig(path string, c Config) error {
+	data, err := encodeConfig(c, filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// decodeConfig decodes the data passed into the configuration, using the format of the file extension passed. YAML
+// and TOML are first decoded into generic values and converted to JSON, so that the JSON tags of Config are used
+// for every format. Unknown settings in the data are ignored while decoding, and are all reported in the first
+// error returned.
+func decodeConfig(data []byte, ext string, c *Config) (unknown error, err error) {
+	var v any
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(data, &v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &v)
+	case ".toml":
+		m := map[string]any{}
+		err, v = toml.Unmarshal(data, &m), m
+	default:
+		err = fmt.Errorf("unsupported config format %q", ext)
+	}
+	if err != nil || v == nil {
+		// If v is nil, the file is empty, so all settings keep their default value.
+		return nil, err
+	}
+	if data, err = json.Marshal(v); err != nil {
+		return nil, err
+	}
+	return unknownSettings(v, reflect.TypeOf(*c), ""), json.Unmarshal(data, c)
+}
+
+// unknownSettings returns an error for every key in the decoded value passed that does not match a setting of the
+// type passed.
+func unknownSettings(v any, t reflect.Type, path string) error {
+	if list, ok := v.([]any); ok && t.Kind() == reflect.Slice {
+		var errs []error
+		for i, e := range list {
+			errs = append(errs, unknownSettings(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i)))
+		}
+		return errors.Join(errs...)
+	}
+	m, ok := v.(map[string]any)
+	if t.Kind() != reflect.Struct || !ok {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = t.Field(i).Type
+	}
+	var errs []error
+	for key, e := range m {
+		setting := key
+		if path != "" {
+			setting = path + "." + key
+		}
+		ft, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", setting))
+			continue
+		}
+		errs = append(errs, unknownSettings(e, ft, setting))
+	}
+	return errors.Join(errs...)
+}
+
+// encodeConfig encodes the configuration passed using the format of the file extension passed.
+func encodeConfig(c Config, ext string) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	ext = strings.ToLower(ext)
+	if ext == ".json" {
+		return data, nil
+	}
+
+	var v map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	normaliseNumbers(v)
+	switch ext {
+	case ".yaml", ".yml":
+		return yaml.Marshal(v)
+	case ".toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported config format %q", ext)
+}
+
+// normaliseNumbers replaces all json.Number values in the value passed with integers, or floats if they are not
+// whole numbers, so that they are encoded as numbers in other formats.
+func normaliseNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normaliseNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = normaliseNumbers(e)
+		}
+	}
+	return v
+}
+
+// applyEnv overrides the settings of the configuration passed with the environment variables passed, in the format
+// of "KEY=value". String settings use the value as is, lists of strings may be comma separated, and all other
+// settings are decoded from the value as JSON. Variables prefixed with EnvPrefix that do not match a setting are
+// skipped, and their names are returned.
+func applyEnv(c *Config, environ []string) (unknown []string, err error) {
+	fields := make(map[string]reflect.Value)
+	collectEnvFields(reflect.ValueOf(c).Elem(), strings.TrimSuffix(EnvPrefix, "_"), fields)
+
+	var errs []error
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		if err := setEnvField(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return unknown, errors.Join(errs...)
+}
+
+// collectEnvFields adds the settings in the struct value passed to the map passed, keyed by the name of the
+// environment variable that overrides them.
+func collectEnvFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "_" + strings.ToUpper(name)
+		if f := v.Field(i); f.Kind() == reflect.Struct {
+			collectEnvFields(f, key, fields)
+		} else {
+			fields[key] = f
+		}
+	}
+}
+
+// setEnvField sets the value of a setting from the value of an environment variable.
+func setEnvField(field reflect.Value, value string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(value, "["):
+		var list []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		field.Set(reflect.ValueOf(list).Convert(field.Type()))
+		return nil
+	}
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		return fmt.Errorf("invalid value %q for %s setting", value, field.Type())
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
@@ -0,0 +1,221 @@
+package portal
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		data    string
+		unknown []string
+		wantErr bool
+		check   func(c Config) bool
+	}{
+		{
+			name: "json",
+			ext:  ".json",
+			data: `{"network": {"motd": "Hello"}, "servers": [{"name": "lobby", "address": "127.0.0.1:19133"}]}`,
+			check: func(c Config) bool {
+				return c.Network.MOTD == "Hello" && len(c.Servers) == 1 && c.Servers[0].Name == "lobby"
+			},
+		},
+		{
+			name:  "yaml",
+			ext:   ".yml",
+			data:  "network:\n  motd: Hello\nhealth_check:\n  interval: 10\n",
+			check: func(c Config) bool { return c.Network.MOTD == "Hello" && c.HealthCheck.Interval == 10 },
+		},
+		{
+			name:  "toml",
+			ext:   ".TOML",
+			data:  "[network]\nmotd = \"Hello\"\n\n[queue.priorities]\nlobby = 3\n",
+			check: func(c Config) bool { return c.Network.MOTD == "Hello" && c.Queue.Priorities["lobby"] == 3 },
+		},
+		{
+			name:  "empty file keeps defaults",
+			ext:   ".yaml",
+			data:  "",
+			check: func(c Config) bool { return reflect.DeepEqual(c, DefaultConfig()) },
+		},
+		{
+			name:    "unknown settings",
+			ext:     ".json",
+			data:    `{"network": {"motd": "Hello", "port": 1}, "unknown": true, "servers": [{"name": "lobby", "host": "x"}]}`,
+			unknown: []string{"network.port", "servers[0].host", "unknown"},
+			check:   func(c Config) bool { return c.Network.MOTD == "Hello" },
+		},
+		{
+			name:    "invalid syntax",
+			ext:     ".json",
+			data:    `{"network": `,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			ext:     ".ini",
+			data:    "motd=Hello",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			unknown, err := decodeConfig([]byte(test.data), test.ext, &c)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("decodeConfig() error = %v, want error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if got := settings(unknown); !reflect.DeepEqual(got, test.unknown) {
+				t.Errorf("decodeConfig() unknown settings = %v, want %v", got, test.unknown)
+			}
+			if !test.check(c) {
+				t.Errorf("decodeConfig() decoded unexpected config: %+v", c)
+			}
+		})
+	}
+}
+
+func TestUnknownSettings(t *testing.T) {
+	type inner struct {
+		Name string `json:"name"`
+	}
+	type config struct {
+		Inner inner          `json:"inner"`
+		List  []inner        `json:"list"`
+		Map   map[string]int `json:"map,omitempty"`
+	}
+	tests := []struct {
+		name string
+		v    any
+		want []string
+	}{
+		{name: "known", v: map[string]any{"inner": map[string]any{"name": "a"}}},
+		{name: "unknown top level", v: map[string]any{"other": 1}, want: []string{"other"}},
+		{name: "unknown nested", v: map[string]any{"inner": map[string]any{"other": 1}}, want: []string{"inner.other"}},
+		{
+			name: "unknown in list",
+			v:    map[string]any{"list": []any{map[string]any{"name": "a"}, map[string]any{"other": 1}}},
+			want: []string{"list[1].other"},
+		},
+		{name: "map keys are not settings", v: map[string]any{"map": map[string]any{"anything": 1}}},
+		{name: "mismatched type", v: map[string]any{"inner": "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := settings(unknownSettings(test.v, reflect.TypeOf(config{}), "")); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("unknownSettings() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		unknown []string
+		wantErr bool
+		check   func(c Config) bool
+	}{
+		{
+			name:    "string",
+			environ: []string{"PORTAL_NETWORK_COMMUNICATION_SECRET=secret=value"},
+			check:   func(c Config) bool { return c.Network.Communication.Secret == "secret=value" },
+		},
+		{
+			name:    "number and bool",
+			environ: []string{"PORTAL_HEALTH_CHECK_INTERVAL=7", "PORTAL_QUEUE_ENABLED=true"},
+			check:   func(c Config) bool { return c.HealthCheck.Interval == 7 && c.Queue.Enabled },
+		},
+		{
+			name:    "comma separated list",
+			environ: []string{"PORTAL_WHITELIST_PLAYERS=Steve, Alex,,"},
+			check:   func(c Config) bool { return reflect.DeepEqual(c.Whitelist.Players, []string{"Steve", "Alex"}) },
+		},
+		{
+			name:    "json list and map",
+			environ: []string{`PORTAL_CAPACITY_BYPASS=["a,b"]`, `PORTAL_QUEUE_PRIORITIES={"steve":2}`},
+			check: func(c Config) bool {
+				return reflect.DeepEqual(c.Capacity.Bypass, []string{"a,b"}) && c.Queue.Priorities["steve"] == 2
+			},
+		},
+		{
+			name:    "other variables are ignored",
+			environ: []string{"HOME=/root", "PORTAL=1", "NETWORK_MOTD=x"},
+			check:   func(c Config) bool { return reflect.DeepEqual(c, DefaultConfig()) },
+		},
+		{
+			name:    "unknown setting",
+			environ: []string{"PORTAL_NETWORK_PORT=19132", "PORTAL_NETWORK_MOTD=Hello"},
+			unknown: []string{"PORTAL_NETWORK_PORT"},
+			check:   func(c Config) bool { return c.Network.MOTD == "Hello" },
+		},
+		{
+			name:    "sections cannot be set",
+			environ: []string{"PORTAL_NETWORK={}"},
+			unknown: []string{"PORTAL_NETWORK"},
+			check:   func(c Config) bool { return true },
+		},
+		{
+			name:    "invalid value",
+			environ: []string{"PORTAL_HEALTH_CHECK_INTERVAL=often"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			unknown, err := applyEnv(&c, test.environ)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("applyEnv() error = %v, want error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(unknown, test.unknown) {
+				t.Errorf("applyEnv() unknown = %v, want %v", unknown, test.unknown)
+			}
+			if !test.check(c) {
+				t.Errorf("applyEnv() produced unexpected config: %+v", c)
+			}
+		})
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	c := DefaultConfig()
+	c.Bans.File = ""
+	c.Whitelist.File = filepath.Join(string(filepath.Separator), "abs", "whitelist.json")
+	c.resolvePaths("data")
+
+	if want := filepath.Join("data", DefaultConfig().Logger.File); c.Logger.File != want {
+		t.Errorf("logger.file = %q, want %q", c.Logger.File, want)
+	}
+	if c.Bans.File != "" {
+		t.Errorf("empty bans.file was resolved to %q", c.Bans.File)
+	}
+	if want := filepath.Join(string(filepath.Separator), "abs", "whitelist.json"); c.Whitelist.File != want {
+		t.Errorf("absolute whitelist.file = %q, want %q", c.Whitelist.File, want)
+	}
+}
+
+// settings returns the settings reported as unknown in the error passed, sorted by name.
+func settings(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var names []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		names = append(names, strings.TrimSuffix(line, ": unknown setting"))
+	}
+	sort.Strings(names)
+	return names
+}
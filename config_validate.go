@@ -0,0 +1,152 @@
+package portal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for invalid addresses, log levels, directories and other values. All problems
+// found are returned together in a single error, or nil if the configuration is valid.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(setting string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", setting, err))
+		}
+	}
+
+	check("network.address", validateAddress(c.Network.Address))
+	check("network.communication.address", validateAddress(c.Network.Communication.Address))
+	if _, err := logrus.ParseLevel(c.Logger.Level); err != nil {
+		check("logger.level", fmt.Errorf("unknown log level %q", c.Logger.Level))
+	}
+	check("logger.file", validateFile(c.Logger.File))
+	if c.PlayerLatency.Report && c.PlayerLatency.UpdateInterval <= 0 {
+		check("player_latency.update_interval", errors.New("must be positive"))
+	}
+
+	names := make(map[string]struct{}, len(c.Servers))
+	for i, s := range c.Servers {
+		setting := fmt.Sprintf("servers[%d]", i)
+		if s.Name == "" {
+			check(setting+".name", errors.New("must not be empty"))
+		} else if _, ok := names[strings.ToLower(s.Name)]; ok {
+			check(setting+".name", fmt.Errorf("duplicate server name %q", s.Name))
+		}
+		names[strings.ToLower(s.Name)] = struct{}{}
+		check(setting+".address", validateAddress(s.Address))
+		if s.Weight < 0 {
+			check(setting+".weight", errors.New("must not be negative"))
+		}
+		if s.MaxPlayers < 0 {
+			check(setting+".max_players", errors.New("must not be negative"))
+		}
+	}
+
+	switch strings.ToLower(c.LoadBalancer.Strategy) {
+	case "", "split", "round_robin", "weighted", "random", "least_connections":
+	case "sticky":
+		check("load_balancer.sticky_file", validateFile(c.LoadBalancer.StickyFile))
+	default:
+		check("load_balancer.strategy", fmt.Errorf("unknown load balancing strategy %q", c.LoadBalancer.Strategy))
+	}
+	if c.HealthCheck.Enabled {
+		if c.HealthCheck.Interval <= 0 {
+			check("health_check.interval", errors.New("must be positive"))
+		}
+		if c.HealthCheck.Timeout <= 0 {
+			check("health_check.timeout", errors.New("must be positive"))
+		}
+	}
+	if c.Queue.Enabled && c.Queue.ServerAddress != "" {
+		check("queue.server_address", validateAddress(c.Queue.ServerAddress))
+	}
+	check("maintenance.file", validateFile(c.Maintenance.File))
+	check("bans.file", validateFile(c.Bans.File))
+	check("whitelist.file", validateFile(c.Whitelist.File))
+	check("resource_packs.directory", validateDirectory(c.ResourcePacks.Directory))
+	if c.Metrics.Enabled {
+		check("metrics.address", validateAddress(c.Metrics.Address))
+		if !strings.HasPrefix(c.Metrics.Path, "/") {
+			check("metrics.path", fmt.Errorf("invalid path %q: must start with a slash", c.Metrics.Path))
+		}
+	}
+	if c.API.Enabled {
+		check("api.address", validateAddress(c.API.Address))
+		if c.API.Token == "" {
+			check("api.token", errors.New("must not be empty"))
+		}
+	}
+	if c.Health.Enabled {
+		check("health.address", validateAddress(c.Health.Address))
+	}
+	if c.Shutdown.FallbackAddress != "" {
+		check("shutdown.fallback_address", validateAddress(c.Shutdown.FallbackAddress))
+	}
+	if c.Shutdown.Timeout <= 0 {
+		// With a timeout of zero, the deadline would pass before any session could be drained.
+		check("shutdown.timeout", errors.New("must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateAddress checks if the address passed is in the format of "ip:port" with a valid port. The IP may be
+// empty or a hostname.
+func validateAddress(address string) error {
+	if address == "" {
+		return errors.New("must not be empty")
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: expected the format \"ip:port\"", address)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("invalid port %q in address %q", port, address)
+	}
+	return nil
+}
+
+// validateDirectory checks if the path passed is a directory, or does not exist yet. An empty path is valid.
+func validateDirectory(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// validateFile checks if the directory the file at the path passed would be stored in exists, and that the path is
+// not a directory itself. An empty path is valid.
+func validateFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("directory %s does not exist", dir)
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
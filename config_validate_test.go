@@ -0,0 +1,112 @@
+package portal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "default", modify: func(c *Config) {}},
+		{
+			name:   "addresses",
+			modify: func(c *Config) { c.Network.Address, c.Network.Communication.Address = "19132", ":0" },
+			want:   []string{"network.address", "network.communication.address"},
+		},
+		{
+			name:   "log level",
+			modify: func(c *Config) { c.Logger.Level = "loud" },
+			want:   []string{"logger.level"},
+		},
+		{
+			name: "servers",
+			modify: func(c *Config) {
+				c.Servers = []StaticServer{
+					{Name: "lobby", Address: "127.0.0.1:19133"},
+					{Name: "Lobby", Address: "127.0.0.1:19134", Weight: -1},
+					{Address: "127.0.0.1:19135", MaxPlayers: -1},
+				}
+			},
+			want: []string{"servers[1].name", "servers[1].weight", "servers[2].name", "servers[2].max_players"},
+		},
+		{
+			name:   "load balancer",
+			modify: func(c *Config) { c.LoadBalancer.Strategy = "fastest" },
+			want:   []string{"load_balancer.strategy"},
+		},
+		{
+			name: "sticky file in missing directory",
+			modify: func(c *Config) {
+				c.LoadBalancer.Strategy, c.LoadBalancer.StickyFile = "sticky", filepath.Join(dir, "missing", "sticky.json")
+			},
+			want: []string{"load_balancer.sticky_file"},
+		},
+		{
+			name: "files",
+			modify: func(c *Config) {
+				c.Logger.File, c.Bans.File, c.ResourcePacks.Directory = dir, filepath.Join(file, "bans.json"), file
+			},
+			want: []string{"logger.file", "bans.file", "resource_packs.directory"},
+		},
+		{
+			name: "intervals",
+			modify: func(c *Config) {
+				c.HealthCheck.Enabled, c.HealthCheck.Interval, c.HealthCheck.Timeout = true, 0, -1
+				c.PlayerLatency.Report, c.PlayerLatency.UpdateInterval = true, 0
+			},
+			want: []string{"player_latency.update_interval", "health_check.interval", "health_check.timeout"},
+		},
+		{
+			name: "disabled sections are not checked",
+			modify: func(c *Config) {
+				c.HealthCheck.Enabled, c.HealthCheck.Interval = false, 0
+				c.API.Enabled, c.API.Token = false, ""
+				c.Metrics.Enabled, c.Metrics.Path = false, "metrics"
+			},
+		},
+		{
+			name: "http servers",
+			modify: func(c *Config) {
+				c.API.Enabled, c.API.Token = true, ""
+				c.Metrics.Enabled, c.Metrics.Path = true, "metrics"
+				c.Health.Enabled, c.Health.Address = true, "localhost"
+			},
+			want: []string{"metrics.path", "api.token", "health.address"},
+		},
+		{
+			name:   "shutdown",
+			modify: func(c *Config) { c.Shutdown.Timeout, c.Shutdown.FallbackAddress = 0, "example.com" },
+			want:   []string{"shutdown.fallback_address", "shutdown.timeout"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			test.modify(&c)
+			err := c.Validate()
+
+			var got []string
+			if err != nil {
+				for _, line := range strings.Split(err.Error(), "\n") {
+					setting, _, _ := strings.Cut(line, ": ")
+					got = append(got, setting)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("Validate() reported %v, want %v (error: %v)", got, test.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/api"
+	"github.com/paroxity/portal/event"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// static holds the files of the web interface of the dashboard.
+//
+//go:embed static
+var static embed.FS
+
+// stateInterval is the interval at which the state of the proxy is sent to the dashboard, so that values that change
+// without an event, such as latencies and server health, are kept up to date.
+const stateInterval = time.Second * 2
+
+// Dashboard serves a web dashboard that shows the online players, servers and recent log lines of the proxy, and
+// allows players to be transferred and kicked. It is built on top of the admin API, which it uses to authenticate
+// moderators and to perform actions, so the API must be served on the same address under /api/.
+type Dashboard struct {
+	p    *portal.Portal
+	api  *api.Handler
+	logs *LogBuffer
+	mux  *http.ServeMux
+
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+// New creates a dashboard for the proxy passed, which authenticates moderators using the token of the admin API
+// passed. The recent lines of the log buffer passed are shown on the dashboard. It may be nil, in which case no log
+// lines are shown.
+func New(p *portal.Portal, a *api.Handler, logs *LogBuffer) *Dashboard {
+	d := &Dashboard{p: p, api: a, logs: logs, mux: http.NewServeMux(), subs: make(map[chan struct{}]struct{})}
+
+	files, _ := fs.Sub(static, "static")
+	d.mux.Handle("GET /", http.FileServerFS(files))
+	d.mux.HandleFunc("POST /login", d.login)
+	d.mux.HandleFunc("POST /logout", d.logout)
+	d.mux.HandleFunc("GET /events", d.events)
+
+	bus := p.Bus()
+	event.Subscribe(bus, func(portal.PlayerJoinEvent) { d.changed() })
+	event.Subscribe(bus, func(portal.PlayerQuitEvent) { d.changed() })
+	event.Subscribe(bus, func(portal.TransferCompletedEvent) { d.changed() })
+	event.Subscribe(bus, func(portal.ServerRegisteredEvent) { d.changed() })
+	event.Subscribe(bus, func(portal.ServerUnregisteredEvent) { d.changed() })
+	return d
+}
+
+// ServeHTTP ...
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// login checks the token submitted by a moderator and stores it in a cookie, which authenticates the requests made
+// by the dashboard to the admin API.
+func (d *Dashboard) login(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	if !d.api.ValidToken(token) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     api.TokenCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Strict prevents other sites from making authenticated requests to the API through the browser of a
+		// moderator.
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logout removes the cookie holding the token of the moderator.
+func (d *Dashboard) logout(w http.ResponseWriter, _ *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: api.TokenCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// state is the state of the proxy sent to the dashboard.
+type state struct {
+	Sessions []api.Session `json:"sessions"`
+	Servers  []api.Server  `json:"servers"`
+}
+
+// events streams the state of the proxy and new log lines to the dashboard as server-sent events. The state is sent
+// when players join, quit or transfer, when servers are registered or unregistered, and at a regular interval.
+func (d *Dashboard) events(w http.ResponseWriter, r *http.Request) {
+	if !d.api.Authorized(r) {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	var lines <-chan string
+	if d.logs != nil {
+		backlog, ch, unsubscribe := d.logs.subscribe()
+		defer unsubscribe()
+		for _, line := range backlog {
+			writeEvent(w, "log", line)
+		}
+		lines = ch
+	}
+	changes, unsubscribe := d.subscribe()
+	defer unsubscribe()
+
+	t := time.NewTicker(stateInterval)
+	defer t.Stop()
+	for {
+		writeEvent(w, "state", state{Sessions: api.Sessions(d.p), Servers: api.Servers(d.p)})
+		flusher.Flush()
+
+	wait:
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case line := <-lines:
+				writeEvent(w, "log", line)
+				flusher.Flush()
+			case <-changes:
+				break wait
+			case <-t.C:
+				break wait
+			}
+		}
+	}
+}
+
+// subscribe returns a channel that receives a value when the state of the proxy changed. The function returned
+// must be called to stop receiving changes.
+func (d *Dashboard) subscribe() (<-chan struct{}, func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	d.subs[ch] = struct{}{}
+	return ch, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.subs, ch)
+	}
+}
+
+// changed notifies every open dashboard that the state of the proxy changed.
+func (d *Dashboard) changed() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for ch := range d.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// A change is already pending for the dashboard.
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with the name passed and the value passed encoded as JSON.
+func writeEvent(w http.ResponseWriter, name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
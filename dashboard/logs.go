@@ -0,0 +1,75 @@
+package dashboard
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+)
+
+// colours matches the ANSI colour codes in log lines, which are stripped before lines are shown on the dashboard.
+var colours = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// LogBuffer is a writer that keeps the most recent lines written to it, so that they can be shown on the
+// dashboard. It may be used as the output of a logger, usually along with other writers using io.MultiWriter.
+type LogBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	size    int
+	partial []byte
+	subs    map[chan string]struct{}
+}
+
+// NewLogBuffer creates a LogBuffer that keeps the last size lines written to it.
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{size: size, subs: make(map[chan string]struct{})}
+}
+
+// Write ...
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.partial = append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i == -1 {
+			break
+		}
+		line := colours.ReplaceAllString(string(bytes.TrimRight(b.partial[:i], " \r")), "")
+		b.partial = b.partial[i+1:]
+
+		b.lines = append(b.lines, line)
+		if len(b.lines) > b.size {
+			b.lines = b.lines[len(b.lines)-b.size:]
+		}
+		for ch := range b.subs {
+			select {
+			case ch <- line:
+			default:
+				// The subscriber is not keeping up, so the line is dropped for it rather than blocking the logger.
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines currently held by the buffer, from oldest to newest.
+func (b *LogBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.lines...)
+}
+
+// subscribe returns the lines currently held by the buffer and a channel on which every line written afterwards is
+// sent. The function returned must be called to stop receiving lines.
+func (b *LogBuffer) subscribe() ([]string, <-chan string, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan string, 64)
+	b.subs[ch] = struct{}{}
+	return append([]string(nil), b.lines...), ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, ch)
+	}
+}
@@ -0,0 +1,171 @@
+"use strict";
+
+// maxLogLines is the maximum amount of log lines kept on the page.
+const maxLogLines = 500;
+
+const $ = id => document.getElementById(id);
+
+let state = {sessions: [], servers: []};
+let events = null;
+
+// el creates an element with the tag, properties and children passed.
+function el(tag, props = {}, ...children) {
+    const e = Object.assign(document.createElement(tag), props);
+    e.append(...children);
+    return e;
+}
+
+// request performs a request to the admin API and throws an error with the message of the API if it failed.
+async function request(method, path, body) {
+    const res = await fetch(path, {
+        method,
+        headers: body ? {"Content-Type": "application/json"} : {},
+        body: body ? JSON.stringify(body) : undefined,
+    });
+    if (res.status === 401) {
+        showLogin();
+        throw new Error("not logged in");
+    }
+    if (!res.ok) {
+        const data = await res.json().catch(() => ({}));
+        throw new Error(data.error || res.statusText);
+    }
+    return res.status === 204 ? null : res.json();
+}
+
+function showLogin() {
+    if (events) {
+        events.close();
+        events = null;
+    }
+    $("main").classList.add("hidden");
+    $("logout").classList.add("hidden");
+    $("login").classList.remove("hidden");
+    $("status").textContent = "Logged out";
+}
+
+function showMain() {
+    $("login").classList.add("hidden");
+    $("main").classList.remove("hidden");
+    $("logout").classList.remove("hidden");
+    connect();
+}
+
+// connect opens the event stream of the dashboard, which sends the state of the proxy and new log lines.
+function connect() {
+    events = new EventSource("events");
+    events.addEventListener("open", () => {
+        // The recent log lines are sent again every time the stream is (re)opened.
+        $("logs").textContent = "";
+        $("status").textContent = "Live";
+    });
+    events.addEventListener("state", e => {
+        state = JSON.parse(e.data);
+        render();
+    });
+    events.addEventListener("log", e => appendLog(JSON.parse(e.data)));
+    events.addEventListener("error", async () => {
+        $("status").textContent = "Reconnecting...";
+        // EventSource does not expose the status code, so check if the token is still valid.
+        const res = await fetch("api/servers").catch(() => null);
+        if (res && res.status === 401) {
+            showLogin();
+        }
+    });
+}
+
+function render() {
+    const players = state.sessions.length;
+    $("total").textContent = `${players} player${players === 1 ? "" : "s"} online`;
+
+    $("servers").replaceChildren(...state.servers.map(srv => el("tr", {},
+        el("td", {textContent: srv.name}),
+        el("td", {textContent: srv.group}),
+        el("td", {textContent: srv.address}),
+        el("td", {}, el("span", {
+            className: "badge " + (srv.healthy ? "healthy" : "unhealthy"),
+            textContent: srv.healthy ? "Healthy" : "Unhealthy",
+        })),
+        el("td", {textContent: srv.max_players > 0 ? `${srv.players}/${srv.max_players}` : `${srv.players}`}),
+        el("td", {textContent: `${srv.latency_ms}ms`}),
+    )));
+    renderPlayers();
+}
+
+function renderPlayers() {
+    // Rendering is skipped while a server is being picked for a transfer, so that the selection is not lost.
+    if (document.activeElement && document.activeElement.tagName === "SELECT") {
+        return;
+    }
+    const query = $("search").value.trim().toLowerCase();
+    const sessions = state.sessions.filter(s => !query ||
+        s.name.toLowerCase().includes(query) || s.uuid.includes(query) || s.server.toLowerCase().includes(query));
+
+    $("players").replaceChildren(...sessions.map(s => {
+        const servers = el("select", {}, ...state.servers
+            .filter(srv => srv.name !== s.server)
+            .map(srv => el("option", {value: srv.name, textContent: srv.name})));
+        const transfer = el("button", {textContent: "Transfer", disabled: s.transferring});
+        transfer.addEventListener("click", () => act(transfer, "POST",
+            `api/sessions/${encodeURIComponent(s.name)}/transfer`, {server: servers.value}));
+
+        const kick = el("button", {className: "danger", textContent: "Kick"});
+        kick.addEventListener("click", () => {
+            const reason = prompt(`Kick ${s.name} with reason:`, "Kicked by an operator");
+            if (reason !== null) {
+                act(kick, "POST", `api/sessions/${encodeURIComponent(s.name)}/kick`, {reason});
+            }
+        });
+        return el("tr", {},
+            el("td", {textContent: s.name, title: s.uuid}),
+            el("td", {textContent: s.transferring ? `${s.server} (transferring)` : s.server}),
+            el("td", {textContent: `${s.latency_ms}ms`}),
+            el("td", {}, servers, " ", transfer, " ", kick),
+        );
+    }));
+}
+
+// act performs an action on the admin API, disabling the button passed while it is in progress.
+async function act(button, method, path, body) {
+    button.disabled = true;
+    try {
+        await request(method, path, body);
+    } catch (e) {
+        alert(e.message);
+    } finally {
+        button.disabled = false;
+    }
+}
+
+function appendLog(line) {
+    const logs = $("logs");
+    const atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 5;
+    logs.append(line + "\n");
+    while (logs.childNodes.length > maxLogLines) {
+        logs.firstChild.remove();
+    }
+    if (atBottom) {
+        logs.scrollTop = logs.scrollHeight;
+    }
+}
+
+$("search").addEventListener("input", renderPlayers);
+
+$("login-form").addEventListener("submit", async e => {
+    e.preventDefault();
+    const res = await fetch("login", {method: "POST", body: new URLSearchParams({token: $("token").value})});
+    if (!res.ok) {
+        $("login-error").textContent = "Invalid token.";
+        return;
+    }
+    $("token").value = "";
+    $("login-error").textContent = "";
+    showMain();
+});
+
+$("logout").addEventListener("click", async () => {
+    await fetch("logout", {method: "POST"});
+    showLogin();
+});
+
+request("GET", "api/servers").then(showMain, () => showLogin());
@@ -0,0 +1,60 @@
+package event
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Bus is an event bus which passes published events to the functions subscribed to their type. Events may be of
+// any type, and subscribers receive only the events of the type they subscribed to.
+type Bus struct {
+	mu          sync.RWMutex
+	seq         uint64
+	subscribers map[reflect.Type][]subscriber
+}
+
+// subscriber is a function subscribed to events of a specific type on a Bus.
+type subscriber struct {
+	id uint64
+	f  any
+}
+
+// NewBus creates a new, empty Bus.
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[reflect.Type][]subscriber)}
+}
+
+// Subscribe subscribes the function f to all events of type E published on the bus passed. Subscribers are called
+// synchronously in the order they subscribed. The function returned removes the subscription when called.
+func Subscribe[E any](b *Bus, f func(e E)) (unsubscribe func()) {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	id := b.seq
+	b.subscribers[t] = append(b.subscribers[t], subscriber{id: id, f: f})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subscribers := b.subscribers[t]
+		for i, s := range subscribers {
+			if s.id == id {
+				b.subscribers[t] = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish passes the event e to all functions subscribed to events of type E on the bus passed.
+func Publish[E any](b *Bus, e E) {
+	b.mu.RLock()
+	subscribers := b.subscribers[reflect.TypeOf((*E)(nil)).Elem()]
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		s.f.(func(E))(e)
+	}
+}
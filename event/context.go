@@ -17,6 +17,11 @@ func (ctx *Context) Cancel() {
 	ctx.cancel = true
 }
 
+// Cancelled returns if the context has been cancelled.
+func (ctx *Context) Cancelled() bool {
+	return ctx.cancel
+}
+
 // Continue calls the function f if the context is not cancelled. If it is cancelled, Continue will return
 // immediately.
 // These functions are not generally useful for handling events. See After() for executing code after the
@@ -0,0 +1,172 @@
+package portal
+
+import (
+	"github.com/paroxity/portal/event"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/paroxity/portal/socket"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"net"
+)
+
+// PlayerPreLoginEvent is published on the event bus when a player has connected to the proxy, before a session is
+// created for them and before any server is dialed. Subscribers may deny the login, pick the server or group the
+// player first joins, or delay the join of the player. Like other events it is published as a value, and the
+// changes made through its methods are shared by every copy of the event.
+type PlayerPreLoginEvent struct {
+	Ctx          *event.Context
+	Conn         *minecraft.Conn
+	IdentityData login.IdentityData
+	ClientData   login.ClientData
+	Address      net.Addr
+
+	state *preLogin
+}
+
+// preLogin holds the changes made to a PlayerPreLoginEvent by its subscribers.
+type preLogin struct {
+	message string
+	server  *server.Server
+	group   string
+	delays  []func()
+}
+
+// Deny denies the login of the player, disconnecting them with the message passed.
+func (e PlayerPreLoginEvent) Deny(message string) {
+	e.state.message = message
+	e.Ctx.Cancel()
+}
+
+// Server returns the server the player will first join, or nil if it is picked by the load balancer.
+func (e PlayerPreLoginEvent) Server() *server.Server {
+	return e.state.server
+}
+
+// SetServer sets the server the player will first join, bypassing the load balancer.
+func (e PlayerPreLoginEvent) SetServer(srv *server.Server) {
+	e.state.server, e.state.group = srv, ""
+}
+
+// Group returns the group the load balancer picks the first server of the player from, or an empty string if it
+// may pick a server from any group.
+func (e PlayerPreLoginEvent) Group() string {
+	return e.state.group
+}
+
+// SetGroup sets the group the load balancer picks the first server of the player from.
+func (e PlayerPreLoginEvent) SetGroup(group string) {
+	e.state.server, e.state.group = nil, group
+}
+
+// Delay delays the join of the player until the function passed has returned. Delayed functions are called in
+// order once every subscriber has handled the event, and may still deny the login or change the server of the
+// player. Other players are not held up by a delayed join.
+func (e PlayerPreLoginEvent) Delay(f func()) {
+	e.state.delays = append(e.state.delays, f)
+}
+
+// RejectReason is the reason a player was not allowed to join the proxy.
+type RejectReason string
+
+const (
+	// RejectReasonBanned is used when the player is banned.
+	RejectReasonBanned RejectReason = "banned"
+	// RejectReasonMaintenance is used when the proxy is in maintenance mode and the player may not bypass it.
+	RejectReasonMaintenance RejectReason = "maintenance"
+	// RejectReasonWhitelist is used when the player is not allowed to join by the whitelist.
+	RejectReasonWhitelist RejectReason = "whitelist"
+	// RejectReasonDenied is used when the login was denied by a subscriber of PlayerPreLoginEvent.
+	RejectReasonDenied RejectReason = "denied"
+	// RejectReasonShutdown is used when the proxy started shutting down while the player was joining.
+	RejectReasonShutdown RejectReason = "shutdown"
+	// RejectReasonError is used when no session could be created for the player, for example because no server
+	// was available.
+	RejectReasonError RejectReason = "error"
+)
+
+// PlayerLoginRejectedEvent is published on the event bus when a player that connected to the proxy was not allowed
+// to join, after they have been disconnected.
+type PlayerLoginRejectedEvent struct {
+	Conn   *minecraft.Conn
+	Reason RejectReason
+	// Message is the message the player was disconnected with. It is empty if the reason is RejectReasonError.
+	Message string
+}
+
+// PlayerJoinEvent is published on the event bus when a session has been created for a player that joined the
+// proxy.
+type PlayerJoinEvent struct {
+	Session *session.Session
+}
+
+// PlayerQuitEvent is published on the event bus when the session of a player is closed, regardless of the reason.
+type PlayerQuitEvent struct {
+	Session *session.Session
+}
+
+// ServerRegisteredEvent is published on the event bus when a server is added to the server registry.
+type ServerRegisteredEvent struct {
+	Server *server.Server
+}
+
+// ServerUnregisteredEvent is published on the event bus when a server is removed from the server registry.
+type ServerUnregisteredEvent struct {
+	Server *server.Server
+}
+
+// SocketClientAuthenticatedEvent is published on the event bus when a client has successfully authenticated with
+// the socket server attached to the proxy.
+type SocketClientAuthenticatedEvent struct {
+	Client *socket.Client
+}
+
+// TransferCompletedEvent is published on the event bus when a session has finished transferring from one server to
+// another.
+type TransferCompletedEvent struct {
+	Session  *session.Session
+	From, To *server.Server
+}
+
+// sessionHandler is the monitor of every session on the proxy, which publishes the events of the session on the
+// event bus.
+type sessionHandler struct {
+	session.NopHandler
+	bus *event.Bus
+	s   *session.Session
+}
+
+// HandleTransferComplete ...
+func (h *sessionHandler) HandleTransferComplete(from, to *server.Server) {
+	event.Publish(h.bus, TransferCompletedEvent{Session: h.s, From: from, To: to})
+}
+
+// HandleQuit ...
+func (h *sessionHandler) HandleQuit() {
+	event.Publish(h.bus, PlayerQuitEvent{Session: h.s})
+}
+
+// registryHandler publishes the changes to the server registry on the event bus.
+type registryHandler struct {
+	bus *event.Bus
+}
+
+// HandleServerAdd ...
+func (h registryHandler) HandleServerAdd(srv *server.Server) {
+	event.Publish(h.bus, ServerRegisteredEvent{Server: srv})
+}
+
+// HandleServerRemove ...
+func (h registryHandler) HandleServerRemove(srv *server.Server) {
+	event.Publish(h.bus, ServerUnregisteredEvent{Server: srv})
+}
+
+// socketHandler publishes the events of the socket server on the event bus.
+type socketHandler struct {
+	bus *event.Bus
+}
+
+// HandleClientAuthenticate ...
+func (h socketHandler) HandleClientAuthenticate(c *socket.Client) {
+	event.Publish(h.bus, SocketClientAuthenticatedEvent{Client: c})
+}
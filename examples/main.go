@@ -1,111 +1,45 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"github.com/paroxity/portal"
-	"github.com/paroxity/portal/internal"
-	portallog "github.com/paroxity/portal/log"
-	"github.com/paroxity/portal/session"
-	"github.com/paroxity/portal/socket"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
 	"github.com/sandertv/gophertunnel/minecraft"
-	"github.com/sandertv/gophertunnel/minecraft/text"
-	"github.com/sirupsen/logrus"
-	"io/ioutil"
+	"log/slog"
+	"net"
 	"os"
-	"time"
 )
 
+// This example shows how portal can be embedded in another program. The cmd/portal binary wires up every
+// subsystem of the proxy using a configuration file, and should be used to run a standalone proxy instead.
 func main() {
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{
-		ForceColors:     true,
-		FullTimestamp:   true,
-		TimestampFormat: "15:04:05",
-	})
-	conf := readConfig(logger)
-	if conf.Logger.File != "" {
-		fileLogger, err := portallog.New(conf.Logger.File)
-		if err != nil {
-			logger.Fatalf("unable to create file logger: %v", err)
-		}
-		logger.SetOutput(fileLogger)
-	}
-	level, err := logrus.ParseLevel(conf.Logger.Level)
-	if err != nil {
-		logger.Errorf("unable to parse log level '%s': %v", conf.Logger.Level, err)
-		level = logrus.InfoLevel
-	}
-	logger.SetLevel(level)
-
-	resourcePacks, err := portal.LoadResourcePacks(conf.ResourcePacks.Directory)
-	if err != nil {
-		logger.Fatalf("unable to load resource packs: %v", err)
-	}
-	for i, pack := range resourcePacks {
-		key, ok := conf.ResourcePacks.EncryptionKeys[pack.UUID()]
-		if ok {
-			resourcePacks[i] = pack.WithContentKey(key)
-		}
-	}
+	// Any logger implementing logging.Logger may be used. This example logs using log/slog.
+	logger := logging.NewSlog(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
 
 	p := portal.New(portal.Options{
 		Logger: logger,
 
-		Address: conf.Network.Address,
+		Address: ":19132",
+		Servers: []*server.Server{
+			server.NewStatic("lobby", "lobby", "127.0.0.1:19133"),
+		},
 		ListenConfig: minecraft.ListenConfig{
 			StatusProvider: portal.NewMOTDStatusProvider("Portal"),
-
-			ResourcePacks:        resourcePacks,
-			TexturePacksRequired: conf.ResourcePacks.Required,
 		},
-
-		Whitelist: session.NewSimpleWhitelist(conf.Whitelist.Enabled, conf.Whitelist.Players),
 	})
 	if err := p.Listen(); err != nil {
-		logger.Fatalf("failed to listen on %s: %v", conf.Network.Address, err)
-	}
-
-	socketServer := socket.NewDefaultServer(conf.Network.Communication.Address, conf.Network.Communication.Secret, p.SessionStore(), p.ServerRegistry(), logger, conf.Network.ReaderLimits)
-	if err := socketServer.Listen(); err != nil {
-		p.Logger().Fatalf("socket server failed to listen: %v", err)
-	}
-	if conf.PlayerLatency.Report {
-		go socketServer.ReportPlayerLatency(time.Second * time.Duration(conf.PlayerLatency.UpdateInterval))
+		logger.Fatalf("failed to listen: %v", err)
 	}
 
 	for {
 		s, err := p.Accept()
-		if err != nil {
-			s.Disconnect(text.Colourf("<red>%v</red>", err))
-			p.Logger().Errorf("failed to accept connection: %v", err)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			logger.Infof("player could not join: %v", err)
 			continue
 		}
-		_ = s
-	}
-}
-
-func readConfig(logger internal.Logger) portal.Config {
-	c := portal.DefaultConfig()
-	if _, err := os.Stat("config.json"); os.IsNotExist(err) {
-		f, err := os.Create("config.json")
-		if err != nil {
-			logger.Fatalf("error creating config: %v", err)
-		}
-		data, err := json.MarshalIndent(c, "", "\t")
-		if err != nil {
-			logger.Fatalf("error encoding default config: %v", err)
-		}
-		if _, err := f.Write(data); err != nil {
-			logger.Fatalf("error writing encoded default config: %v", err)
-		}
-		_ = f.Close()
-	}
-	data, err := ioutil.ReadFile("config.json")
-	if err != nil {
-		logger.Fatalf("error reading config: %v", err)
-	}
-	if err := json.Unmarshal(data, &c); err != nil {
-		logger.Fatalf("error decoding config: %v", err)
+		logger.Infof("%s joined the proxy", s.Conn().IdentityData().DisplayName)
 	}
-	return c
 }
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"encoding/json"
+	"github.com/paroxity/portal"
+	"net/http"
+)
+
+// New returns an HTTP handler that serves the liveness and readiness probes of the proxy passed, for use by
+// orchestrators. /healthz responds with 200 OK while the proxy is running, and /readyz responds with 200 OK while
+// the proxy is ready for players to join. Both respond with 503 Service Unavailable and the reason otherwise.
+func New(p *portal.Portal) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, p.Live())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, p.Ready())
+	})
+	return mux
+}
+
+// writeStatus writes the result of a probe as the JSON body of the response. The probe passed if the error passed
+// is nil.
+func writeStatus(w http.ResponseWriter, err error) {
+	v := struct {
+		Status string `json:"status"`
+		Reason string `json:"reason,omitempty"`
+	}{Status: "ok"}
+	status := http.StatusOK
+	if err != nil {
+		v.Status, v.Reason, status = "unavailable", err.Error(), http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/socket"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name string
+		// unhealthy is true if the server registered on the proxy is unhealthy. If servers is false, no server is
+		// registered at all.
+		servers, unhealthy bool
+		listen, shutdown   bool
+		// socket is true if a socket server that is not listening is attached to the proxy.
+		socket bool
+		ready  string
+	}{
+		{name: "not listening", servers: true, ready: "listener is not bound"},
+		{name: "ready", servers: true, listen: true},
+		{name: "no servers", listen: true, ready: "no healthy servers are registered"},
+		{name: "unhealthy server", servers: true, unhealthy: true, listen: true, ready: "no healthy servers are registered"},
+		{name: "socket server not listening", servers: true, listen: true, socket: true, ready: "socket server is not listening"},
+		{name: "shutting down", servers: true, listen: true, shutdown: true, ready: "proxy is shutting down"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := logrus.New()
+			l.SetOutput(io.Discard)
+			log := logging.NewLogrus(l)
+			p := portal.New(portal.Options{Logger: log, Address: "127.0.0.1:0"})
+			if test.servers {
+				srv := server.New("lobby", "127.0.0.1:19133")
+				srv.SetHealthy(!test.unhealthy)
+				p.ServerRegistry().AddServer(srv)
+			}
+			if test.socket {
+				p.SetSocketServer(socket.NewDefaultServer("127.0.0.1:0", "", p.SessionStore(), p.ServerRegistry(), log, true))
+			}
+			if test.listen {
+				if err := p.Listen(); err != nil {
+					t.Fatalf("Listen() error = %v", err)
+				}
+				if !test.shutdown {
+					defer p.Shutdown(context.Background())
+				}
+			}
+			if test.shutdown {
+				if err := p.Shutdown(context.Background()); err != nil {
+					t.Fatalf("Shutdown() error = %v", err)
+				}
+			}
+
+			h := New(p)
+			// The proxy is live in every test, as its listener never stops accepting connections by itself.
+			checkProbe(t, h, "/healthz", "")
+			checkProbe(t, h, "/readyz", test.ready)
+		})
+	}
+}
+
+// checkProbe requests the probe at the path passed and checks that it failed for the reason passed, or that it
+// passed if the reason is empty.
+func checkProbe(t *testing.T, h http.Handler, path, reason string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+	want, wantStatus := http.StatusOK, "ok"
+	if reason != "" {
+		want, wantStatus = http.StatusServiceUnavailable, "unavailable"
+	}
+	var body struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("%v: invalid response body: %v", path, err)
+	}
+	if rec.Code != want || body.Status != wantStatus || body.Reason != reason {
+		t.Errorf("%v = %d %q %q, want %d %q %q", path, rec.Code, body.Status, body.Reason, want, wantStatus, reason)
+	}
+	if cache := rec.Header().Get("Cache-Control"); cache != "no-store" {
+		t.Errorf("%v: Cache-Control = %q, want %q", path, cache, "no-store")
+	}
+}
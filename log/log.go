@@ -37,5 +37,10 @@ func (l *Logger) Write(p []byte) (int, error) {
 	}
 
 	cleaned := cleaner.ReplaceAllString(string(p), "")
-	return l.file.WriteString(time.Now().Format("2006-1-2") + " " + cleaned)
+	if _, err := l.file.WriteString(time.Now().Format("2006-1-2") + " " + cleaned); err != nil {
+		return 0, err
+	}
+	// The line written to the file differs in length from p, so the length of p is returned to report that it was
+	// written in full.
+	return len(p), nil
 }
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		file string
+	}{
+		{name: "plain", line: "proxy started\n", file: "proxy started\n"},
+		{name: "coloured", line: "\x1b[31mfailed to dial\x1b[0m\n", file: "failed to dial\n"},
+		{name: "empty", line: "", file: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "proxy.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			l := &Logger{file: f, stdout: io.Discard}
+
+			// The line written to the file is prefixed with the date and stripped of colours, but writers such as
+			// the standard logger treat a length other than that of the line passed as a short write.
+			n, err := l.Write([]byte(test.line))
+			if err != nil || n != len(test.line) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(test.line))
+			}
+			data, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, line, _ := strings.Cut(string(data), " "); line != test.file {
+				t.Errorf("file contains %q, want %q", line, test.file)
+			}
+		})
+	}
+}
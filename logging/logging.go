@@ -0,0 +1,45 @@
+package logging
+
+import "github.com/sirupsen/logrus"
+
+// Logger is used to log errors and info throughout Portal. Any logger implementation that implements Logger may be
+// used by passing it to portal.New, such as a *logrus.Logger, and NewLogrus and NewSlog may be used to adapt logrus
+// and log/slog loggers so that fields are supported.
+type Logger interface {
+	Debugf(format string, v ...any)
+	Infof(format string, v ...any)
+	Errorf(format string, v ...any)
+	Fatalf(format string, v ...any)
+}
+
+// FieldLogger is a Logger that can add fields to the lines it logs. The loggers returned by NewLogrus and NewSlog
+// implement FieldLogger.
+type FieldLogger interface {
+	Logger
+	// With returns a Logger that adds the fields passed to every line it logs. Fields are passed as alternating
+	// keys and values, or as slog.Attr, in the same way as for slog.Logger.With, such as With("player", name).
+	With(args ...any) Logger
+}
+
+// With returns a Logger that adds the fields passed to every line logged by the logger passed, using its With method
+// if it implements FieldLogger. A *logrus.Logger is adapted using NewLogrus. Other loggers do not support fields, so
+// they are returned as is.
+func With(l Logger, args ...any) Logger {
+	switch l := l.(type) {
+	case FieldLogger:
+		return l.With(args...)
+	case *logrus.Logger:
+		return NewLogrus(l).With(args...)
+	}
+	return l
+}
+
+// Warnf logs a warning to the logger passed. Loggers that do not have a Warnf method, such as the Logger interface
+// itself, log the warning as an error instead.
+func Warnf(l Logger, format string, v ...any) {
+	if w, ok := l.(interface{ Warnf(format string, v ...any) }); ok {
+		w.Warnf(format, v...)
+		return
+	}
+	l.Errorf(format, v...)
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"github.com/sirupsen/logrus"
+	"log/slog"
+	"time"
+)
+
+// logrusLogger is a Logger that logs to a logrus logger.
+type logrusLogger struct {
+	l    logrus.FieldLogger
+	root *logrus.Logger
+}
+
+// NewLogrus returns a FieldLogger that logs to the logrus logger passed. Fields added using With are logged as logrus
+// fields.
+func NewLogrus(l *logrus.Logger) FieldLogger {
+	return logrusLogger{l: l, root: l}
+}
+
+// Debugf ...
+func (l logrusLogger) Debugf(format string, v ...any) {
+	l.l.Debugf(format, v...)
+}
+
+// Infof ...
+func (l logrusLogger) Infof(format string, v ...any) {
+	l.l.Infof(format, v...)
+}
+
+// Warnf ...
+func (l logrusLogger) Warnf(format string, v ...any) {
+	l.l.Warnf(format, v...)
+}
+
+// Errorf ...
+func (l logrusLogger) Errorf(format string, v ...any) {
+	l.l.Errorf(format, v...)
+}
+
+// Fatalf ...
+func (l logrusLogger) Fatalf(format string, v ...any) {
+	l.l.Fatalf(format, v...)
+}
+
+// With ...
+func (l logrusLogger) With(args ...any) Logger {
+	// The fields are parsed using a slog record, so that they follow the same rules as for the slog adapter.
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	fields := make(logrus.Fields, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields[a.Key] = a.Value.Resolve().Any()
+		return true
+	})
+	return logrusLogger{l: l.l.WithFields(fields), root: l.root}
+}
+
+// SetLevel sets the level of the logrus logger, which also applies to all loggers returned by With.
+func (l logrusLogger) SetLevel(level logrus.Level) {
+	l.root.SetLevel(level)
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// slogLogger is a Logger that logs to a slog.Logger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlog returns a FieldLogger that logs to the slog.Logger passed. Lines passed to Fatalf are logged at the error
+// level, after which the program exits.
+func NewSlog(l *slog.Logger) FieldLogger {
+	return slogLogger{l: l}
+}
+
+// Debugf ...
+func (s slogLogger) Debugf(format string, v ...any) {
+	s.log(slog.LevelDebug, format, v)
+}
+
+// Infof ...
+func (s slogLogger) Infof(format string, v ...any) {
+	s.log(slog.LevelInfo, format, v)
+}
+
+// Warnf ...
+func (s slogLogger) Warnf(format string, v ...any) {
+	s.log(slog.LevelWarn, format, v)
+}
+
+// Errorf ...
+func (s slogLogger) Errorf(format string, v ...any) {
+	s.log(slog.LevelError, format, v)
+}
+
+// Fatalf ...
+func (s slogLogger) Fatalf(format string, v ...any) {
+	s.log(slog.LevelError, format, v)
+	os.Exit(1)
+}
+
+// With ...
+func (s slogLogger) With(args ...any) Logger {
+	return slogLogger{l: s.l.With(args...)}
+}
+
+// log formats the line and logs it at the level passed, if that level is enabled. The record is passed to the
+// handler directly, as the source of the line would otherwise always be this function.
+func (s slogLogger) log(level slog.Level, format string, v []any) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	_ = s.l.Handler().Handle(ctx, slog.NewRecord(time.Now(), level, fmt.Sprintf(format, v...), 0))
+}
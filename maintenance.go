@@ -0,0 +1,133 @@
+package portal
+
+import (
+	"encoding/json"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Maintenance holds the maintenance mode of the proxy. While maintenance mode is enabled, the MOTD of the proxy is
+// replaced and only players on the bypass list may join. Whether maintenance mode is enabled is saved to a file so
+// that it survives restarts.
+type Maintenance struct {
+	path string
+
+	mu      sync.Mutex
+	enabled bool
+	message string
+	motd    string
+	bypass  []string
+}
+
+// maintenanceData is the data of Maintenance that is saved to its file.
+type maintenanceData struct {
+	Enabled bool `json:"enabled"`
+}
+
+// NewMaintenance creates a maintenance mode which loads its state from and saves it to the file at the path passed.
+// If the path is empty, the state is not persisted. The message passed is shown to players that are refused, and
+// the MOTD passed is shown in the server list, while maintenance mode is enabled. Players with a name or XUID in
+// the bypass list may still join.
+func NewMaintenance(path, message, motd string, bypass []string) (*Maintenance, error) {
+	m := &Maintenance{path: path}
+	m.SetMessage(message)
+	m.SetMOTD(motd)
+	m.SetBypass(bypass)
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var d maintenanceData
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	m.enabled = d.Enabled
+	return m, nil
+}
+
+// Enabled returns if maintenance mode is enabled.
+func (m *Maintenance) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enabled
+}
+
+// SetEnabled enables or disables maintenance mode and saves the state. Portal.SetMaintenance should be used to also
+// disconnect the players that may not bypass maintenance mode.
+func (m *Maintenance) SetEnabled(enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = enabled
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(maintenanceData{Enabled: enabled}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0644)
+}
+
+// Message returns the message shown to players that are refused while maintenance mode is enabled.
+func (m *Maintenance) Message() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.message
+}
+
+// SetMessage sets the message shown to players that are refused while maintenance mode is enabled. If it is empty,
+// a default message is used.
+func (m *Maintenance) SetMessage(message string) {
+	if message == "" {
+		message = text.Colourf("<red>The network is under maintenance</red>")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.message = message
+}
+
+// MOTD returns the MOTD shown in the server list while maintenance mode is enabled.
+func (m *Maintenance) MOTD() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.motd
+}
+
+// SetMOTD sets the MOTD shown in the server list while maintenance mode is enabled. If it is empty, a default MOTD
+// is used.
+func (m *Maintenance) SetMOTD(motd string) {
+	if motd == "" {
+		motd = text.Colourf("<red>Maintenance</red>")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.motd = motd
+}
+
+// SetBypass sets the names or XUIDs of the players that may join while maintenance mode is enabled.
+func (m *Maintenance) SetBypass(bypass []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bypass = append([]string(nil), bypass...)
+}
+
+// Bypasses returns if the player with the identity data passed may join while maintenance mode is enabled.
+func (m *Maintenance) Bypasses(identity login.IdentityData) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.bypass {
+		if strings.EqualFold(v, identity.DisplayName) || (identity.XUID != "" && v == identity.XUID) {
+			return true
+		}
+	}
+	return false
+}
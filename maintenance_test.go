@@ -0,0 +1,110 @@
+package portal
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaintenancePersistence(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		data    string
+		enable  []bool
+		want    bool
+		wantErr bool
+	}{
+		{name: "no file", want: false},
+		{name: "enabled in file", data: `{"enabled": true}`, want: true},
+		{name: "enabled and saved", enable: []bool{true}, want: true},
+		{name: "disabled and saved", data: `{"enabled": true}`, enable: []bool{false}, want: false},
+		{name: "toggled", enable: []bool{true, false, true}, want: true},
+		{name: "invalid file", data: `{"enabled": `, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name+".json")
+			if test.data != "" {
+				if err := os.WriteFile(path, []byte(test.data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m, err := NewMaintenance(path, "", "", nil)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewMaintenance() error = %v, want error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			for _, enabled := range test.enable {
+				if err := m.SetEnabled(enabled); err != nil {
+					t.Fatalf("SetEnabled() error = %v", err)
+				}
+			}
+
+			// The maintenance mode is loaded again, so that the state saved to the file is tested.
+			m, err = NewMaintenance(path, "", "", nil)
+			if err != nil {
+				t.Fatalf("NewMaintenance() error = %v", err)
+			}
+			if m.Enabled() != test.want {
+				t.Errorf("Enabled() = %v, want %v", m.Enabled(), test.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceBypasses(t *testing.T) {
+	m, err := NewMaintenance("", "", "", []string{"Steve", "2535400000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		identity login.IdentityData
+		want     bool
+	}{
+		{name: "name", identity: login.IdentityData{DisplayName: "steve"}, want: true},
+		{name: "xuid", identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000001"}, want: true},
+		{name: "other player", identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000002"}, want: false},
+		{name: "offline player", identity: login.IdentityData{DisplayName: "Alex"}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := m.Bypasses(test.identity); got != test.want {
+				t.Errorf("Bypasses() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceStatusProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		motd    string
+		want    string
+	}{
+		{name: "disabled", enabled: false, motd: "Back soon", want: "Portal"},
+		{name: "enabled", enabled: true, motd: "Back soon", want: "Back soon"},
+		{name: "default motd", enabled: true, want: text.Colourf("<red>Maintenance</red>")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := NewMaintenance("", "", test.motd, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.SetEnabled(test.enabled); err != nil {
+				t.Fatal(err)
+			}
+			p := maintenanceStatusProvider{maintenance: m, provider: NewMOTDStatusProvider("Portal")}
+			if status := p.ServerStatus(1, 10); status.ServerName != test.want || status.PlayerCount != 1 {
+				t.Errorf("ServerStatus() = %q with %d players, want %q with 1 player", status.ServerName, status.PlayerCount, test.want)
+			}
+		})
+	}
+}
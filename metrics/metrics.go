@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/event"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"net/http"
+	"time"
+)
+
+// namespace is the namespace of all metrics exported by the proxy.
+const namespace = "portal"
+
+// Metrics collects the metrics of a proxy and exports them in the Prometheus format. Counters are updated through
+// the event bus of the proxy and monitors of its sessions and packets, while the amount of online players and
+// socket clients is read from the proxy every time the metrics are collected.
+type Metrics struct {
+	p        *portal.Portal
+	registry *prometheus.Registry
+
+	transfersStarted   prometheus.Counter
+	transfersSucceeded prometheus.Counter
+	transfersFailed    prometheus.Counter
+
+	rejections          *prometheus.CounterVec
+	whitelistRejections prometheus.Counter
+
+	packets *prometheus.CounterVec
+	bytes   *prometheus.CounterVec
+	// latency holds the latencies of all sessions, aggregated by the server they are on. Every session is sampled
+	// each time its latency is measured, so every session is weighted equally. A histogram for every session would
+	// add a series per player that joined, which grows without limit, so it is not labelled by session.
+	latency *prometheus.HistogramVec
+
+	sessionsDesc      *prometheus.Desc
+	serverPlayersDesc *prometheus.Desc
+	socketClientsDesc *prometheus.Desc
+}
+
+// New creates the metrics of the proxy passed and starts collecting them. It should be called before the proxy
+// starts listening, as only the sessions created afterwards are measured.
+func New(p *portal.Portal) *Metrics {
+	m := &Metrics{
+		p:        p,
+		registry: prometheus.NewRegistry(),
+
+		transfersStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_started_total",
+			Help:      "The amount of transfers between servers that were started.",
+		}),
+		transfersSucceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_succeeded_total",
+			Help:      "The amount of transfers between servers that completed successfully.",
+		}),
+		transfersFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_failed_total",
+			Help:      "The amount of transfers between servers that failed.",
+		}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "accept_rejections_total",
+			Help:      "The amount of players that connected to the proxy but were not allowed to join, by reason.",
+		}, []string{"reason"}),
+		whitelistRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "whitelist_rejections_total",
+			Help:      "The amount of players that were not allowed to join by the whitelist.",
+		}),
+		packets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "packets_relayed_total",
+			Help:      "The amount of packets relayed between players and servers, by direction.",
+		}, []string{"direction"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_relayed_total",
+			Help:      "The uncompressed size of the packets sent between players and the proxy, by direction.",
+		}, []string{"direction"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "server",
+			Name:      "player_latency_seconds",
+			Help:      "The latency between players and the proxy, aggregated by the server the players are on.",
+			Buckets:   []float64{.01, .025, .05, .1, .15, .2, .3, .5, 1, 2},
+		}, []string{"server"}),
+
+		sessionsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "sessions_online"),
+			"The amount of players online on the proxy.", nil, nil,
+		),
+		serverPlayersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "server", "players_online"),
+			"The amount of players online on a server.", []string{"server", "group"}, nil,
+		),
+		socketClientsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "socket_clients_connected"),
+			"The amount of clients authenticated with the socket server.", nil, nil,
+		),
+	}
+	m.registry.MustRegister(
+		m,
+		m.transfersStarted, m.transfersSucceeded, m.transfersFailed,
+		m.rejections, m.whitelistRejections,
+		m.packets, m.bytes, m.latency,
+	)
+
+	event.Subscribe(p.Bus(), func(e portal.PlayerLoginRejectedEvent) {
+		m.rejections.WithLabelValues(string(e.Reason)).Inc()
+		if e.Reason == portal.RejectReasonWhitelist {
+			m.whitelistRejections.Inc()
+		}
+	})
+	p.AddSessionMonitor(func(s *session.Session) session.Handler {
+		return &handler{m: m, s: s}
+	})
+	// The bytes are counted from the payloads read from and written to the connections of players, so that packets
+	// do not have to be encoded again to find their size.
+	clientbound, serverbound := m.bytes.WithLabelValues("clientbound"), m.bytes.WithLabelValues("serverbound")
+	p.AddPacketMonitor(func(toClient bool, _ packet.Header, payload []byte) {
+		if toClient {
+			clientbound.Add(float64(len(payload)))
+			return
+		}
+		serverbound.Add(float64(len(payload)))
+	})
+	return m
+}
+
+// Registry returns the Prometheus registry the metrics are registered to. Other collectors may be registered to it
+// to export them along with the metrics of the proxy.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns an HTTP handler that serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Describe ...
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.sessionsDesc
+	ch <- m.serverPlayersDesc
+	ch <- m.socketClientsDesc
+}
+
+// Collect ...
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(m.sessionsDesc, prometheus.GaugeValue, float64(len(m.p.SessionStore().All())))
+	for _, srv := range m.p.ServerRegistry().Servers() {
+		ch <- prometheus.MustNewConstMetric(m.serverPlayersDesc, prometheus.GaugeValue, float64(srv.PlayerCount()), srv.Name(), srv.Group())
+	}
+	var clients int
+	if s := m.p.SocketServer(); s != nil {
+		clients = len(s.Clients())
+	}
+	ch <- prometheus.MustNewConstMetric(m.socketClientsDesc, prometheus.GaugeValue, float64(clients))
+}
+
+// handler is the monitor added to every session to update the metrics for the events of the session.
+type handler struct {
+	session.NopHandler
+	m *Metrics
+	s *session.Session
+}
+
+// HandleClientBoundPacket ...
+func (h *handler) HandleClientBoundPacket(ctx *event.Context, _ packet.Packet) {
+	if !ctx.Cancelled() {
+		h.m.relay("clientbound")
+	}
+}
+
+// HandleServerBoundPacket ...
+func (h *handler) HandleServerBoundPacket(ctx *event.Context, _ packet.Packet) {
+	if !ctx.Cancelled() {
+		h.m.relay("serverbound")
+	}
+}
+
+// HandleTransfer ...
+func (h *handler) HandleTransfer(*event.Context, *server.Server) {
+	// Transfers cancelled by other handlers are counted as well, as they are reported to HandleTransferFail.
+	h.m.transfersStarted.Inc()
+}
+
+// HandleTransferComplete ...
+func (h *handler) HandleTransferComplete(*server.Server, *server.Server) {
+	h.m.transfersSucceeded.Inc()
+}
+
+// HandleTransferFail ...
+func (h *handler) HandleTransferFail(_ *server.Server, err error) {
+	if errors.Is(err, session.ErrServerUnhealthy) || errors.Is(err, session.ErrServerFull) {
+		// The transfer was refused before HandleTransfer was called, so it has not been counted as started yet.
+		h.m.transfersStarted.Inc()
+	}
+	h.m.transfersFailed.Inc()
+}
+
+// HandleLatencyUpdate ...
+func (h *handler) HandleLatencyUpdate(latency time.Duration) {
+	var name string
+	if srv := h.s.Server(); srv != nil {
+		name = srv.Name()
+	}
+	h.m.latency.WithLabelValues(name).Observe(latency.Seconds())
+}
+
+// relay counts a packet relayed in the direction passed.
+func (m *Metrics) relay(direction string) {
+	m.packets.WithLabelValues(direction).Inc()
+}
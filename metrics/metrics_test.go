@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"bufio"
+	"github.com/paroxity/portal"
+	"github.com/paroxity/portal/event"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestMetrics returns the metrics of a new proxy that does not log anything.
+func newTestMetrics() (*portal.Portal, *Metrics) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	p := portal.New(portal.Options{Logger: logging.NewLogrus(l)})
+	return p, New(p)
+}
+
+// scrape returns the values of all series served by the handler of the metrics passed, by their name and labels
+// as written in the exposition format, such as `portal_accept_rejections_total{reason="banned"}`.
+func scrape(t *testing.T, m *Metrics) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+		values[line[:i]] = v
+	}
+	return values
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(h *handler)
+		want   map[string]float64
+	}{
+		{
+			name: "relayed packets",
+			handle: func(h *handler) {
+				h.HandleClientBoundPacket(event.C(), nil)
+				h.HandleClientBoundPacket(event.C(), nil)
+				h.HandleServerBoundPacket(event.C(), nil)
+			},
+			want: map[string]float64{
+				`portal_packets_relayed_total{direction="clientbound"}`: 2,
+				`portal_packets_relayed_total{direction="serverbound"}`: 1,
+			},
+		},
+		{
+			name: "cancelled packets",
+			handle: func(h *handler) {
+				ctx := event.C()
+				ctx.Cancel()
+				h.HandleClientBoundPacket(ctx, nil)
+				h.HandleServerBoundPacket(ctx, nil)
+			},
+			want: map[string]float64{
+				`portal_packets_relayed_total{direction="clientbound"}`: 0,
+				`portal_packets_relayed_total{direction="serverbound"}`: 0,
+			},
+		},
+		{
+			name: "transfers",
+			handle: func(h *handler) {
+				h.HandleTransfer(event.C(), nil)
+				h.HandleTransferComplete(nil, nil)
+				h.HandleTransfer(event.C(), nil)
+				h.HandleTransferFail(nil, io.EOF)
+			},
+			want: map[string]float64{
+				"portal_transfers_started_total":   2,
+				"portal_transfers_succeeded_total": 1,
+				"portal_transfers_failed_total":    1,
+			},
+		},
+		{
+			name: "refused transfers",
+			handle: func(h *handler) {
+				h.HandleTransferFail(nil, session.ErrServerFull)
+				h.HandleTransferFail(nil, session.ErrServerUnhealthy)
+			},
+			want: map[string]float64{
+				"portal_transfers_started_total":   2,
+				"portal_transfers_succeeded_total": 0,
+				"portal_transfers_failed_total":    2,
+			},
+		},
+		{
+			name: "latency",
+			handle: func(h *handler) {
+				h.HandleLatencyUpdate(20 * time.Millisecond)
+				h.HandleLatencyUpdate(20 * time.Millisecond)
+				h.HandleLatencyUpdate(400 * time.Millisecond)
+			},
+			want: map[string]float64{
+				`portal_server_player_latency_seconds_count{server=""}`:             3,
+				`portal_server_player_latency_seconds_bucket{server="",le="0.025"}`: 2,
+				`portal_server_player_latency_seconds_bucket{server="",le="0.5"}`:   3,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, m := newTestMetrics()
+			test.handle(&handler{m: m, s: &session.Session{}})
+
+			values := scrape(t, m)
+			for series, want := range test.want {
+				if got := values[series]; got != want {
+					t.Errorf("%v = %v, want %v", series, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRejections(t *testing.T) {
+	p, m := newTestMetrics()
+	for _, reason := range []portal.RejectReason{portal.RejectReasonWhitelist, portal.RejectReasonBanned, portal.RejectReasonWhitelist} {
+		event.Publish(p.Bus(), portal.PlayerLoginRejectedEvent{Reason: reason})
+	}
+
+	values := scrape(t, m)
+	tests := []struct {
+		series string
+		want   float64
+	}{
+		{series: `portal_accept_rejections_total{reason="whitelist"}`, want: 2},
+		{series: `portal_accept_rejections_total{reason="banned"}`, want: 1},
+		{series: "portal_whitelist_rejections_total", want: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.series, func(t *testing.T) {
+			if got := values[test.series]; got != test.want {
+				t.Errorf("%v = %v, want %v", test.series, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	p, m := newTestMetrics()
+	lobby := server.NewInGroup("lobby", "hub", "127.0.0.1:19133")
+	lobby.IncrementPlayerCount()
+	lobby.IncrementPlayerCount()
+	p.ServerRegistry().AddServer(lobby)
+	p.ServerRegistry().AddServer(server.New("survival", "127.0.0.1:19134"))
+
+	values := scrape(t, m)
+	tests := []struct {
+		series string
+		want   float64
+	}{
+		{series: "portal_sessions_online", want: 0},
+		{series: "portal_socket_clients_connected", want: 0},
+		{series: `portal_server_players_online{group="hub",server="lobby"}`, want: 2},
+		{series: `portal_server_players_online{group="",server="survival"}`, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.series, func(t *testing.T) {
+			if got, ok := values[test.series]; !ok || got != test.want {
+				t.Errorf("%v = %v (present: %v), want %v", test.series, got, ok, test.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,19 @@
 package portal
 
 import (
-	"github.com/paroxity/portal/internal"
+	"github.com/paroxity/portal/ban"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
 	"github.com/paroxity/portal/session"
 	"github.com/sandertv/gophertunnel/minecraft"
+	"time"
 )
 
 // Options represents the options that control how the proxy should be set up. After the proxy has been
 // instantiated, the options below are immutable unless instantiated again.
 type Options struct {
 	// Logger represents the logger that will be used for the lifetime of the proxy.
-	Logger internal.Logger
+	Logger logging.Logger
 
 	// Address is the address that the proxy should run on. It should be in the format of "address:port".
 	Address string
@@ -18,10 +21,49 @@ type Options struct {
 	// and add resource packs etc.
 	ListenConfig minecraft.ListenConfig
 
+	// Servers is a list of static servers that are registered when the proxy is created. Static servers are never
+	// removed from the server registry, even if no socket client is attached for them.
+	Servers []*server.Server
+
 	// LoadBalancer is the method used to balance load across the servers on the proxy. It can be used to
 	// change which servers players connect to when they join the proxy.
 	LoadBalancer session.LoadBalancer
 
+	// FallbackServer is the name of the server players are transferred to when the server they are connected to
+	// closes the connection. If the server is not available, or if it is empty, the load balancer is used to find
+	// another server instead.
+	FallbackServer string
+	// DisableFallback disables transferring players to another server when the server they are connected to closes
+	// the connection. If true, players are disconnected from the proxy instead.
+	DisableFallback bool
+
+	// HealthCheckInterval is the interval at which every server is pinged to check if it is still online. If
+	// zero, servers are not health checked.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the time a server has to respond to a health check ping.
+	HealthCheckTimeout time.Duration
+	// HealthCheckThreshold is the amount of health checks a server must fail in a row to be marked as unhealthy.
+	HealthCheckThreshold int
+
+	// CapacityBypass is a list of player names or XUIDs of players that may join servers that have reached their
+	// maximum amount of players.
+	CapacityBypass []string
+
+	// Maintenance is the maintenance mode of the proxy. While it is enabled, the MOTD of the status provider in the
+	// ListenConfig is replaced and only players on its bypass list may join. If nil, maintenance mode is disabled
+	// and its state is not persisted.
+	Maintenance *Maintenance
+
+	// Bans is the list of bans that are enforced when players join the proxy. If nil, an empty list that is not
+	// persisted is used.
+	Bans *ban.List
+
 	// Whitelist is used to limit the proxy to only allow certain players to join.
 	Whitelist session.Whitelist
+
+	// ShutdownMessage is the message shown to players that are disconnected when the proxy shuts down.
+	ShutdownMessage string
+	// ShutdownFallbackAddress is the address players are transferred to when the proxy shuts down. It should
+	// be in the format of "address:port". If empty, players are disconnected with the ShutdownMessage instead.
+	ShutdownFallbackAddress string
 }
@@ -1,59 +1,151 @@
 package portal
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/paroxity/portal/internal"
+	"github.com/paroxity/portal/ban"
+	"github.com/paroxity/portal/event"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/queue"
 	"github.com/paroxity/portal/server"
 	"github.com/paroxity/portal/session"
+	"github.com/paroxity/portal/socket"
 	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sandertv/gophertunnel/minecraft/text"
 	"github.com/sirupsen/logrus"
+	"go.uber.org/atomic"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Portal represents the proxy and controls its functionality.
 type Portal struct {
-	log internal.Logger
+	log logging.Logger
+	bus *event.Bus
 
 	address      string
 	listenConfig minecraft.ListenConfig
 	listener     *minecraft.Listener
+	listening    atomic.Bool
+	// statusProvider is the status provider set in the ListenConfig, before it is wrapped to show the MOTD of the
+	// maintenance mode.
+	statusProvider minecraft.ServerStatusProvider
 
 	sessionStore   *session.Store
 	serverRegistry *server.Registry
 	loadBalancer   session.LoadBalancer
+	maintenance    *Maintenance
+	bans           *ban.List
 	whitelist      session.Whitelist
+	socketServer   socket.Server
+	healthChecker  *server.HealthChecker
+	queue          *queue.Queue
+
+	capacityBypass []string
+
+	fallbackServer  string
+	disableFallback bool
+
+	shutdownMessage         string
+	shutdownFallbackAddress string
+	closing                 atomic.Bool
+	// loginMu guards closing and adding to logins, so that no login is started once Shutdown waits for them.
+	loginMu sync.Mutex
+	logins  sync.WaitGroup
+
+	monitorsMu     sync.RWMutex
+	monitors       []func(s *session.Session) session.Handler
+	packetMonitors []func(clientbound bool, header packet.Header, payload []byte)
+
+	accepted     chan acceptResult
+	acceptClosed chan struct{}
+	acceptOnce   sync.Once
 }
 
 // New instantiates portal using the provided options and returns it. If some options are not set, default
 // values will be used in replacement.
 func New(opts Options) *Portal {
 	if opts.Logger == nil {
-		opts.Logger = logrus.New()
+		opts.Logger = logging.NewLogrus(logrus.New())
 	}
+	bus := event.NewBus()
 	serverRegistry := server.NewDefaultRegistry()
+	serverRegistry.AddMonitor(registryHandler{bus: bus})
+	for _, srv := range opts.Servers {
+		serverRegistry.AddServer(srv)
+	}
 	if opts.LoadBalancer == nil {
 		opts.LoadBalancer = session.NewSplitLoadBalancer(serverRegistry)
 	}
+	if opts.Maintenance == nil {
+		opts.Maintenance, _ = NewMaintenance("", "", "", nil)
+	}
+	if opts.ListenConfig.StatusProvider == nil {
+		opts.ListenConfig.StatusProvider = minecraft.NewStatusProvider("Minecraft Server", "Gophertunnel")
+	}
+	statusProvider := opts.ListenConfig.StatusProvider
+	opts.ListenConfig.StatusProvider = maintenanceStatusProvider{maintenance: opts.Maintenance, provider: opts.ListenConfig.StatusProvider}
+	if opts.Bans == nil {
+		opts.Bans, _ = ban.NewList("")
+	}
 	if opts.Whitelist == nil {
 		opts.Whitelist = session.NewSimpleWhitelist(false, []string{})
 	}
+	if opts.ShutdownMessage == "" {
+		opts.ShutdownMessage = text.Colourf("<red>Proxy is shutting down</red>")
+	}
+	var healthChecker *server.HealthChecker
+	if opts.HealthCheckInterval > 0 {
+		if opts.HealthCheckTimeout <= 0 {
+			opts.HealthCheckTimeout = time.Second * 2
+		}
+		healthChecker = server.NewHealthChecker(serverRegistry, opts.HealthCheckInterval, opts.HealthCheckTimeout, opts.HealthCheckThreshold, opts.Logger)
+	}
 	return &Portal{
 		log: opts.Logger,
+		bus: bus,
 
-		address:      opts.Address,
-		listenConfig: opts.ListenConfig,
+		address:        opts.Address,
+		listenConfig:   opts.ListenConfig,
+		statusProvider: statusProvider,
 
 		sessionStore:   session.NewDefaultStore(),
 		serverRegistry: serverRegistry,
+		healthChecker:  healthChecker,
 		loadBalancer:   opts.LoadBalancer,
+		maintenance:    opts.Maintenance,
+		bans:           opts.Bans,
 		whitelist:      opts.Whitelist,
+
+		capacityBypass: opts.CapacityBypass,
+
+		fallbackServer:  opts.FallbackServer,
+		disableFallback: opts.DisableFallback,
+
+		shutdownMessage:         opts.ShutdownMessage,
+		shutdownFallbackAddress: opts.ShutdownFallbackAddress,
+
+		accepted:     make(chan acceptResult),
+		acceptClosed: make(chan struct{}),
 	}
 }
 
 // Logger returns the global logger used by the proxy.
-func (p *Portal) Logger() internal.Logger {
+func (p *Portal) Logger() logging.Logger {
 	return p.log
 }
 
+// Bus returns the event bus of the proxy, on which the proxy-wide events are published. Functions may be subscribed
+// to these events using event.Subscribe.
+func (p *Portal) Bus() *event.Bus {
+	return p.bus
+}
+
 // SessionStore returns the session store provided to portal. It is used to store all the open sessions.
 func (p *Portal) SessionStore() *session.Store {
 	return p.sessionStore
@@ -64,46 +156,404 @@ func (p *Portal) ServerRegistry() *server.Registry {
 	return p.serverRegistry
 }
 
+// HealthChecker returns the health checker that pings the servers in the server registry, or nil if health checks
+// are disabled.
+func (p *Portal) HealthChecker() *server.HealthChecker {
+	return p.healthChecker
+}
+
 // LoadBalancer returns the load balancer that handles the server a player joins when they first connect to the proxy.
 func (p *Portal) LoadBalancer() session.LoadBalancer {
 	return p.loadBalancer
 }
 
 // SetLoadBalancer sets the load balancer that handles the server a player joins when they first connect to the proxy.
+// If the load balancer implements io.Closer, it is closed when the proxy shuts down.
 func (p *Portal) SetLoadBalancer(loadBalancer session.LoadBalancer) {
 	p.loadBalancer = loadBalancer
 }
 
+// SetMOTD changes the MOTD shown for the proxy in the server list. An error is returned if the status provider in
+// the ListenConfig of the proxy is not a *MOTDStatusProvider.
+func (p *Portal) SetMOTD(motd string) error {
+	provider, ok := p.statusProvider.(*MOTDStatusProvider)
+	if !ok {
+		return fmt.Errorf("status provider %T does not support changing the MOTD", p.statusProvider)
+	}
+	provider.MOTD(motd)
+	return nil
+}
+
+// Maintenance returns the maintenance mode of the proxy.
+func (p *Portal) Maintenance() *Maintenance {
+	return p.maintenance
+}
+
+// SetMaintenance enables or disables maintenance mode. If kick is true and maintenance mode is enabled, all online
+// players that may not bypass maintenance mode are disconnected with the maintenance message.
+func (p *Portal) SetMaintenance(enabled, kick bool) error {
+	if err := p.maintenance.SetEnabled(enabled); err != nil {
+		return err
+	}
+	if enabled {
+		p.Logger().Infof("maintenance mode has been enabled")
+	} else {
+		p.Logger().Infof("maintenance mode has been disabled")
+	}
+	if !enabled || !kick {
+		return nil
+	}
+	for _, s := range p.sessionStore.All() {
+		if !p.maintenance.Bypasses(s.Conn().IdentityData()) {
+			s.Disconnect(p.maintenance.Message())
+		}
+	}
+	return nil
+}
+
+// Whitelist returns the whitelist used to decide which players are allowed to join the proxy.
+func (p *Portal) Whitelist() session.Whitelist {
+	return p.whitelist
+}
+
+// SetWhitelistEnabled enables or disables the whitelist of the proxy. When it is enabled, all online players that
+// are not allowed by the whitelist are disconnected. An error is returned if the whitelist cannot be toggled at
+// runtime.
+func (p *Portal) SetWhitelistEnabled(enabled bool) error {
+	w, ok := p.whitelist.(interface{ SetEnabled(enabled bool) error })
+	if !ok {
+		return fmt.Errorf("whitelist %T cannot be toggled at runtime", p.whitelist)
+	}
+	if err := w.SetEnabled(enabled); err != nil {
+		return err
+	}
+	if enabled {
+		p.EnforceWhitelist()
+	}
+	return nil
+}
+
+// EnforceWhitelist disconnects all online players that are not allowed to join by the whitelist of the proxy.
+func (p *Portal) EnforceWhitelist() {
+	for _, s := range p.sessionStore.All() {
+		if ok, m := p.whitelist.Authorize(s.Conn()); !ok {
+			p.Logger().Infof("%s is not whitelisted and has been disconnected", s.Conn().IdentityData().DisplayName)
+			s.Disconnect(m)
+		}
+	}
+}
+
+// Bans returns the list of bans that are enforced when players join the proxy. Bans added directly to the list
+// do not affect players that are already online, Ban should be used to also disconnect them.
+func (p *Portal) Bans() *ban.List {
+	return p.bans
+}
+
+// Ban adds the ban passed to the ban list of the proxy and disconnects every online player the ban applies to.
+func (p *Portal) Ban(b ban.Ban) error {
+	if err := p.bans.Add(b); err != nil {
+		return err
+	}
+	for _, s := range p.sessionStore.All() {
+		if b.Matches(s.Conn().IdentityData(), s.Conn().RemoteAddr()) {
+			p.Logger().Infof("%s has been banned by %s: %s", s.Conn().IdentityData().DisplayName, b.Issuer, b.Reason)
+			s.Disconnect(b.Message())
+		}
+	}
+	return nil
+}
+
+// Unban removes the ban with the kind and target passed from the ban list of the proxy. False is returned if no
+// such ban existed.
+func (p *Portal) Unban(kind ban.Kind, target string) (bool, error) {
+	return p.bans.Remove(kind, target)
+}
+
+// SocketServer returns the socket server attached to the proxy, or nil if no socket server has been attached.
+func (p *Portal) SocketServer() socket.Server {
+	return p.socketServer
+}
+
+// SetSocketServer attaches a socket server to the proxy so that it is closed when the proxy is shut down. A monitor
+// is added to the socket server that publishes its events on the event bus, so that the handler of the socket
+// server may still be set using Handle.
+func (p *Portal) SetSocketServer(socketServer socket.Server) {
+	if socketServer != nil {
+		socketServer.AddMonitor(socketHandler{bus: p.bus})
+	}
+	p.socketServer = socketServer
+}
+
+// Queue returns the queue players are added to when every server is full, or nil if no queue has been set.
+func (p *Portal) Queue() *queue.Queue {
+	return p.queue
+}
+
+// SetQueue sets the queue players are added to when every server is full. If nil, players are disconnected when
+// every server is full instead.
+func (p *Portal) SetQueue(q *queue.Queue) {
+	p.queue = q
+}
+
+// AddSessionMonitor adds a function that creates a handler observing every event of a session. The function is
+// called for every session created after it was added, and the handler it returns is called with PriorityMonitor
+// for as long as the session is open, regardless of other handlers set on the session.
+func (p *Portal) AddSessionMonitor(f func(s *session.Session) session.Handler) {
+	p.monitorsMu.Lock()
+	defer p.monitorsMu.Unlock()
+	p.monitors = append(p.monitors, f)
+}
+
+// AddPacketMonitor adds a function that is called with the header and encoded payload of every packet read from or
+// written to the connection of a player, before it is compressed. clientbound is true for packets written to the
+// player. Packet monitors must be added before the proxy starts listening.
+func (p *Portal) AddPacketMonitor(f func(clientbound bool, header packet.Header, payload []byte)) {
+	p.monitorsMu.Lock()
+	defer p.monitorsMu.Unlock()
+	p.packetMonitors = append(p.packetMonitors, f)
+}
+
+// sessionMonitors returns the functions creating the monitors of a new session.
+func (p *Portal) sessionMonitors() []func(s *session.Session) session.Handler {
+	p.monitorsMu.RLock()
+	defer p.monitorsMu.RUnlock()
+	monitors := []func(s *session.Session) session.Handler{func(s *session.Session) session.Handler {
+		return &sessionHandler{bus: p.bus, s: s}
+	}}
+	return append(monitors, p.monitors...)
+}
+
 // Listen starts to listen on the set address and allows connections from minecraft clients. An error is
 // returned if the listener failed to listen.
 func (p *Portal) Listen() error {
-	l, err := p.listenConfig.Listen("raknet", p.address)
+	conf := p.listenConfig
+	p.monitorsMu.RLock()
+	monitors := p.packetMonitors
+	p.monitorsMu.RUnlock()
+
+	var local net.Addr
+	listened := make(chan struct{})
+	if len(monitors) > 0 {
+		packetFunc := conf.PacketFunc
+		conf.PacketFunc = func(header packet.Header, payload []byte, src, dst net.Addr) {
+			if packetFunc != nil {
+				packetFunc(header, payload, src, dst)
+			}
+			<-listened
+			// Every connection returns the address of the listener as its local address, which is the source of the
+			// packets written to the player.
+			clientbound := src == local
+			for _, f := range monitors {
+				f(clientbound, header, payload)
+			}
+		}
+	}
+	l, err := conf.Listen("raknet", p.address)
 	if err != nil {
+		close(listened)
 		return err
 	}
+	local = l.Addr()
+	close(listened)
 	p.listener = l
+	p.listening.Store(true)
+	go p.accept()
+	if p.healthChecker != nil {
+		go p.healthChecker.Run()
+	}
+	return nil
+}
+
+// Live returns nil if the proxy is running. An error is returned if the listener stopped accepting connections
+// without the proxy being shut down, in which case the proxy should be restarted.
+func (p *Portal) Live() error {
+	select {
+	case <-p.acceptClosed:
+		if !p.closing.Load() {
+			return errors.New("listener stopped accepting connections")
+		}
+	default:
+	}
+	return nil
+}
+
+// Ready returns nil if the proxy is ready for players to join: the listener is bound, the attached socket server,
+// if any, is listening, and at least one healthy server is registered. Otherwise, an error describing why the proxy
+// is not ready is returned. The proxy stops being ready as soon as it starts shutting down, before sessions are
+// drained.
+func (p *Portal) Ready() error {
+	if p.closing.Load() {
+		return errors.New("proxy is shutting down")
+	}
+	if !p.listening.Load() {
+		return errors.New("listener is not bound")
+	}
+	if s, ok := p.socketServer.(interface{ Listening() bool }); ok && !s.Listening() {
+		return errors.New("socket server is not listening")
+	}
+	if len(p.serverRegistry.HealthyServers()) == 0 {
+		return errors.New("no healthy servers are registered")
+	}
 	return nil
 }
 
 // Accept accepts a fully connected (on Minecraft layer) connection which is ready to receive and send packets. If the
-// listener is closed or the player failed to spawn in then an error will be returned. When an error is returned the
-// session is also returned, but it may be incomplete and contain nil values.
+// listener is closed then net.ErrClosed is returned. If the player failed to join, an error is also returned along
+// with the session, but it may be incomplete and contain nil values. Connections are handled concurrently, so a
+// player whose join is delayed does not hold up the players joining after them.
 func (p *Portal) Accept() (*session.Session, error) {
-	p.Logger().Debugf("waiting to accept...")
 	if p.listener == nil {
 		return nil, fmt.Errorf("no active listener")
 	}
-	conn, err := p.listener.Accept()
-	p.Logger().Debugf("accepted connection")
-	if err != nil {
-		return nil, err
+	select {
+	case r := <-p.accepted:
+		return r.s, r.err
+	case <-p.acceptClosed:
+		return nil, net.ErrClosed
+	}
+}
+
+// acceptResult is the result of handling a connection accepted by the listener.
+type acceptResult struct {
+	s   *session.Session
+	err error
+}
+
+// accept accepts connections from the listener until it is closed, and handles each of them in a new goroutine.
+func (p *Portal) accept() {
+	defer p.closeAccept()
+	for {
+		p.Logger().Debugf("waiting to accept...")
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if !p.closing.Load() {
+				p.Logger().Errorf("listener stopped accepting connections: %v", err)
+			}
+			return
+		}
+		p.Logger().Debugf("accepted connection")
+
+		go func(c *minecraft.Conn) {
+			s, err := p.login(c)
+			select {
+			case p.accepted <- acceptResult{s: s, err: err}:
+			case <-p.acceptClosed:
+				if s == nil {
+					_ = c.Close()
+				}
+			}
+		}(conn.(*minecraft.Conn))
+	}
+}
+
+// closeAccept stops the results of accepted connections from being returned by Accept.
+func (p *Portal) closeAccept() {
+	p.acceptOnce.Do(func() {
+		close(p.acceptClosed)
+	})
+}
+
+// login handles a connection accepted by the listener. The bans, maintenance mode and whitelist are checked and
+// PlayerPreLoginEvent is published, after which a session is created for the player.
+func (p *Portal) login(c *minecraft.Conn) (*session.Session, error) {
+	if !p.beginLogin() {
+		p.reject(c, RejectReasonShutdown, p.shutdownMessage)
+		return nil, net.ErrClosed
+	}
+	defer p.logins.Done()
+
+	if b, ok := p.bans.Find(c.IdentityData(), c.RemoteAddr()); ok {
+		p.reject(c, RejectReasonBanned, b.Message())
+		return nil, fmt.Errorf("player is banned: %s", b.Reason)
+	}
+	if p.maintenance.Enabled() && !p.maintenance.Bypasses(c.IdentityData()) {
+		p.reject(c, RejectReasonMaintenance, p.maintenance.Message())
+		return nil, fmt.Errorf("proxy is under maintenance")
 	}
-	c := conn.(*minecraft.Conn)
 	if ok, m := p.whitelist.Authorize(c); !ok {
-		_ = p.Disconnect(c, m)
+		p.reject(c, RejectReasonWhitelist, m)
 		return nil, fmt.Errorf("player is not whitelisted: %s", m)
 	}
-	return session.New(c, p.sessionStore, p.loadBalancer, p.log)
+	if w, ok := p.whitelist.(*session.PersistentWhitelist); ok {
+		if err := w.BindXUID(c.IdentityData()); err != nil {
+			p.Logger().Errorf("failed to save the XUID of %s to the whitelist: %v", c.IdentityData().DisplayName, err)
+		}
+	}
+	e := PlayerPreLoginEvent{
+		Ctx:          event.C(),
+		Conn:         c,
+		IdentityData: c.IdentityData(),
+		ClientData:   c.ClientData(),
+		Address:      c.RemoteAddr(),
+		state:        &preLogin{},
+	}
+	event.Publish(p.bus, e)
+	for _, f := range e.state.delays {
+		f()
+	}
+	if e.Ctx.Cancelled() {
+		p.reject(c, RejectReasonDenied, e.state.message)
+		return nil, fmt.Errorf("login of %s was denied: %s", e.IdentityData.DisplayName, e.state.message)
+	}
+	if p.closing.Load() {
+		p.reject(c, RejectReasonShutdown, p.shutdownMessage)
+		return nil, net.ErrClosed
+	}
+
+	var loadBalancer, fallback session.LoadBalancer = p.loadBalancer, nil
+	if p.queue != nil {
+		loadBalancer = p.queue
+	}
+	if !p.disableFallback {
+		fallback = session.NewFallbackLoadBalancer(p.serverRegistry, p.fallbackServer, loadBalancer)
+	}
+	s, err := session.NewWithConfig(c, session.Config{
+		Store:          p.sessionStore,
+		LoadBalancer:   loadBalancer,
+		Server:         e.state.server,
+		Group:          e.state.group,
+		Fallback:       fallback,
+		Log:            p.log,
+		Monitors:       p.sessionMonitors(),
+		BypassCapacity: p.bypassesCapacity(c),
+	})
+	if err != nil {
+		event.Publish(p.bus, PlayerLoginRejectedEvent{Conn: c, Reason: RejectReasonError})
+		return s, err
+	}
+	event.Publish(p.bus, PlayerJoinEvent{Session: s})
+	return s, nil
+}
+
+// beginLogin adds a login to the logins that Shutdown waits for. False is returned if the proxy is shutting down,
+// in which case the login must not continue.
+func (p *Portal) beginLogin() bool {
+	p.loginMu.Lock()
+	defer p.loginMu.Unlock()
+	if p.closing.Load() {
+		return false
+	}
+	p.logins.Add(1)
+	return true
+}
+
+// reject disconnects a connection that may not join the proxy with the message passed, and publishes a
+// PlayerLoginRejectedEvent for it.
+func (p *Portal) reject(c *minecraft.Conn, reason RejectReason, message string) {
+	_ = p.Disconnect(c, message)
+	event.Publish(p.bus, PlayerLoginRejectedEvent{Conn: c, Reason: reason, Message: message})
+}
+
+// bypassesCapacity returns if the player of the connection passed may join servers that have reached their maximum
+// amount of players.
+func (p *Portal) bypassesCapacity(conn *minecraft.Conn) bool {
+	identity := conn.IdentityData()
+	for _, v := range p.capacityBypass {
+		if strings.EqualFold(v, identity.DisplayName) || (identity.XUID != "" && v == identity.XUID) {
+			return true
+		}
+	}
+	return false
 }
 
 // Disconnect disconnects a Minecraft Conn passed by first sending a disconnect with the message passed, and
@@ -115,3 +565,93 @@ func (p *Portal) Disconnect(conn *minecraft.Conn, message string) error {
 	}
 	return p.listener.Disconnect(conn, message)
 }
+
+// Shutdown gracefully shuts down the proxy. The listener is closed so that no new connections are accepted, after
+// which every open session is either disconnected with the configured shutdown message or transferred to the
+// configured fallback address. Players that are still logging in are waited for and drained as well. Once all
+// sessions have been drained, the attached socket server is closed, which also stops the latency reporter. If the
+// context expires before all sessions are drained, the remaining sessions are closed and the context error is
+// returned.
+// The proxy reports that it is not ready as soon as Shutdown is called.
+func (p *Portal) Shutdown(ctx context.Context) error {
+	// The lock ensures that no login is added to the logins waited for below once the proxy is closing.
+	p.loginMu.Lock()
+	closing := p.closing.CAS(false, true)
+	p.loginMu.Unlock()
+	if !closing {
+		return errors.New("proxy is already shutting down")
+	}
+	p.Logger().Infof("shutting down proxy...")
+	if p.listener != nil {
+		_ = p.listener.Close()
+	}
+	p.closeAccept()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		// Logins that started before the proxy started shutting down are waited for, so that their sessions are
+		// drained as well.
+		p.logins.Wait()
+		for {
+			sessions := p.sessionStore.All()
+			if len(sessions) == 0 {
+				return
+			}
+			var w sync.WaitGroup
+			w.Add(len(sessions))
+			for _, s := range sessions {
+				go func(s *session.Session) {
+					defer w.Done()
+					p.drain(s)
+				}(s)
+			}
+			w.Wait()
+		}
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		p.Logger().Debugf("all sessions have been drained")
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.Logger().Errorf("failed to drain all sessions before shutdown: %v", err)
+		// The remaining sessions are closed, so that their connections to the servers are closed as well.
+		for _, s := range p.sessionStore.All() {
+			s.Close()
+		}
+	}
+
+	if p.healthChecker != nil {
+		_ = p.healthChecker.Close()
+	}
+	if p.queue != nil {
+		_ = p.queue.Close()
+	}
+	if closer, ok := p.LoadBalancer().(io.Closer); ok {
+		// Load balancers such as the sticky load balancer save their state when closed.
+		if closeErr := closer.Close(); closeErr != nil {
+			p.Logger().Errorf("failed to close load balancer: %v", closeErr)
+		}
+	}
+	if p.socketServer != nil {
+		if closeErr := p.socketServer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// drain removes a session from the proxy during shutdown, either by transferring it to the fallback address or by
+// disconnecting it with the shutdown message.
+func (p *Portal) drain(s *session.Session) {
+	if p.shutdownFallbackAddress != "" {
+		err := s.Redirect(p.shutdownFallbackAddress)
+		if err == nil {
+			return
+		}
+		p.Logger().Errorf("failed to redirect session to fallback address %s: %v", p.shutdownFallbackAddress, err)
+	}
+	s.Disconnect(p.shutdownMessage)
+}
@@ -0,0 +1,425 @@
+package queue
+
+import (
+	"errors"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sandertv/gophertunnel/minecraft/text"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target represents what a queued player is waiting for. If both fields are empty, the player is waiting for any
+// server on the proxy to have a slot available.
+type Target struct {
+	// Server is the name of the server the player is waiting for.
+	Server string
+	// Group is the name of the group the player is waiting for. It is only used if Server is empty.
+	Group string
+}
+
+// String returns a human-readable representation of the target.
+func (t Target) String() string {
+	if t.Server != "" {
+		return t.Server
+	} else if t.Group != "" {
+		return t.Group
+	}
+	return "the network"
+}
+
+// entry is a single player waiting in the queue.
+type entry struct {
+	s        *session.Session
+	target   Target
+	priority int
+	joined   time.Time
+
+	// moving is the server the session is being moved to, or nil if it is not being moved. The entry keeps its
+	// position in the queue and holds a slot on the server until the transfer has completed or failed.
+	moving *server.Server
+}
+
+// Queue holds players that are waiting for a slot to open up on a server, a group or the network as a whole. Players
+// that join while every server is full are parked, either on a designated queue server or in a waiting world hosted
+// by the proxy, until they can be moved. Players are moved in order of their priority tier, and in the order they
+// joined the queue within a tier.
+//
+// Queue implements session.LoadBalancer so that it can wrap the load balancer of the proxy: if the wrapped load
+// balancer does not find a server for a joining player, the player is queued and sent to the parking server instead.
+type Queue struct {
+	log          logging.Logger
+	store        *session.Store
+	registry     *server.Registry
+	loadBalancer session.LoadBalancer
+	priorities   map[string]int
+
+	parking *server.Server
+	world   *waitingWorld
+
+	templateMu sync.Mutex
+	tmpl       minecraft.GameData
+	hasTmpl    bool
+
+	mu      sync.Mutex
+	entries []*entry
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// New creates a new Queue which moves players to the servers in the registry passed using the load balancer passed.
+// Queued players are parked on the server with the address passed, or in a waiting world hosted by the proxy if the
+// address is empty. The priorities map holds the priority tier of players by name or XUID, where players with a
+// higher tier are moved first. Players that are not in the map have a tier of 0.
+func New(store *session.Store, registry *server.Registry, loadBalancer session.LoadBalancer, address string, priorities map[string]int, log logging.Logger) (*Queue, error) {
+	q := &Queue{
+		log:          log,
+		store:        store,
+		registry:     registry,
+		loadBalancer: loadBalancer,
+		priorities:   make(map[string]int, len(priorities)),
+
+		closed: make(chan struct{}),
+	}
+	for k, v := range priorities {
+		q.priorities[strings.ToLower(k)] = v
+	}
+
+	if address == "" {
+		w, err := newWaitingWorld(q.template, log)
+		if err != nil {
+			return nil, err
+		}
+		q.world = w
+		address = w.Addr()
+	}
+	q.parking = server.NewStatic("queue", "", address)
+	return q, nil
+}
+
+// Parking returns the server that queued players are parked on. It is not part of the server registry.
+func (q *Queue) Parking() *server.Server {
+	return q.parking
+}
+
+// FindServer ...
+func (q *Queue) FindServer(s *session.Session) *server.Server {
+	if srv := q.loadBalancer.FindServer(s); srv != nil {
+		return srv
+	}
+	if s.Server() == q.parking {
+		return nil
+	}
+	q.Enqueue(s, Target{})
+	return q.parking
+}
+
+// FindServerInGroup ...
+func (q *Queue) FindServerInGroup(s *session.Session, group string) *server.Server {
+	if srv := session.FindServerInGroup(q.loadBalancer, s, group); srv != nil || s.Server() != nil {
+		return srv
+	}
+	if len(q.registry.ServersInGroup(group)) == 0 {
+		return nil
+	}
+	// The session has only just joined the proxy, so it is parked until a server in the group has space for it.
+	q.Enqueue(s, Target{Group: group})
+	return q.parking
+}
+
+// Record ...
+func (q *Queue) Record(s *session.Session, srv *server.Server) {
+	if r, ok := q.loadBalancer.(session.Recorder); ok && srv != q.parking {
+		r.Record(s, srv)
+	}
+}
+
+// Enqueue adds the session to the queue for the target passed. If the session was already queued, its target is
+// updated but it keeps its position.
+func (q *Queue) Enqueue(s *session.Session, target Target) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, e := range q.entries {
+		if e.s == s {
+			e.target = target
+			return
+		}
+	}
+	q.entries = append(q.entries, &entry{s: s, target: target, priority: q.priority(s.Conn().IdentityData()), joined: time.Now()})
+	q.sort()
+	q.log.Infof("%s has joined the queue for %s", s.Conn().IdentityData().DisplayName, target)
+}
+
+// Dequeue removes the session from the queue. It returns false if the session was not queued.
+func (q *Queue) Dequeue(s *session.Session) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.remove(s)
+}
+
+// remove removes the session from the queue. It returns false if the session was not queued. The mutex must be held
+// when it is called.
+func (q *Queue) remove(s *session.Session) bool {
+	for i, e := range q.entries {
+		if e.s == s {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Position returns the position of the session in the queue for its target, starting at 1, along with the amount of
+// players queued for the same target. If the session is not queued, 0 is returned for both.
+func (q *Queue) Position(s *session.Session) (position, total int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var target *Target
+	for _, e := range q.entries {
+		if e.s == s {
+			target = &e.target
+			break
+		}
+	}
+	if target == nil {
+		return 0, 0
+	}
+	for _, e := range q.entries {
+		if e.target != *target {
+			continue
+		}
+		total++
+		if e.s == s {
+			position = total
+		}
+	}
+	return position, total
+}
+
+// Len returns the amount of players in the queue.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// Run moves queued players to their target as soon as slots open up and updates the queue position shown to each
+// player, once every interval. It blocks until the queue is closed.
+func (q *Queue) Run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-q.closed:
+			return
+		}
+		q.refreshTemplate()
+		q.prune()
+		q.move()
+		q.notify()
+	}
+}
+
+// Close stops the queue and the waiting world hosted by the proxy, if any.
+func (q *Queue) Close() (err error) {
+	q.closeOnce.Do(func() {
+		close(q.closed)
+		if q.world != nil {
+			err = q.world.Close()
+		}
+	})
+	return
+}
+
+// prune removes sessions that are no longer open from the queue.
+func (q *Queue) prune() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := q.entries[:0]
+	for _, e := range q.entries {
+		if s, ok := q.store.Load(e.s.UUID()); ok && s == e.s {
+			entries = append(entries, e)
+		}
+	}
+	q.entries = entries
+}
+
+// move moves the players at the front of the queue of each target to their target if a slot is available. Players
+// further back in the queue for a target are only moved once the players in front of them have been moved. Each
+// player is moved in a separate goroutine, so that a slow transfer does not hold up the rest of the queue.
+func (q *Queue) move() {
+	q.mu.Lock()
+	entries := make([]*entry, len(q.entries))
+	copy(entries, q.entries)
+	q.mu.Unlock()
+
+	blocked := make(map[Target]struct{})
+	for _, e := range entries {
+		if _, ok := blocked[e.target]; ok {
+			continue
+		}
+		q.mu.Lock()
+		moving := e.moving != nil
+		q.mu.Unlock()
+		if moving {
+			continue
+		}
+		if q.reached(e) {
+			q.Dequeue(e.s)
+			continue
+		}
+		srv := q.find(e)
+		if srv == nil || !q.reserve(e, srv) {
+			blocked[e.target] = struct{}{}
+			continue
+		}
+		go q.transfer(e, srv)
+	}
+}
+
+// reserve holds a slot on the server passed for the entry while it is being moved. It returns false if the entry is
+// no longer queued, or if the slots left on the server are already held by other entries being moved to it.
+func (q *Queue) reserve(e *entry, srv *server.Server) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, pending := false, 0
+	for _, other := range q.entries {
+		if other == e {
+			queued = true
+		} else if other.moving == srv {
+			pending++
+		}
+	}
+	if !queued {
+		return false
+	}
+	if maxPlayers := srv.MaxPlayers(); maxPlayers > 0 && !e.s.BypassesCapacity() && srv.PlayerCount()+pending >= maxPlayers {
+		return false
+	}
+	e.moving = srv
+	return true
+}
+
+// transfer moves the session of the entry passed to the server passed. The entry is removed from the queue once the
+// transfer has completed. If the transfer fails, the entry keeps its position and the slot is released, so that the
+// player is moved again once a slot is available.
+func (q *Queue) transfer(e *entry, srv *server.Server) {
+	err := e.s.Transfer(srv)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e.moving = nil
+	if err == nil || errors.Is(err, session.ErrSessionClosed) {
+		q.remove(e.s)
+		return
+	}
+	q.log.Errorf("failed to move %s from the queue to %s: %v", e.s.Conn().IdentityData().DisplayName, srv.Name(), err)
+}
+
+// reached checks if the session of the entry passed has already reached its target in some other way, for example by
+// being transferred by a server.
+func (q *Queue) reached(e *entry) bool {
+	current := e.s.Server()
+	switch {
+	case current == q.parking:
+		return false
+	case e.target.Server != "":
+		return strings.EqualFold(current.Name(), e.target.Server)
+	case e.target.Group != "":
+		return strings.EqualFold(current.Group(), e.target.Group)
+	}
+	return true
+}
+
+// find finds a server with an available slot for the entry passed, or returns nil if there is none.
+func (q *Queue) find(e *entry) *server.Server {
+	switch {
+	case e.target.Server != "":
+		srv, ok := q.registry.Server(e.target.Server)
+		if !ok || !srv.Healthy() || (srv.Full() && !e.s.BypassesCapacity()) || srv == e.s.Server() {
+			return nil
+		}
+		return srv
+	case e.target.Group != "":
+		return session.FindServerInGroup(q.loadBalancer, e.s, e.target.Group)
+	}
+	return q.loadBalancer.FindServer(e.s)
+}
+
+// notify shows each queued player their position in the queue in their action bar.
+func (q *Queue) notify() {
+	q.mu.Lock()
+	totals := make(map[Target]int)
+	positions := make(map[*entry]int, len(q.entries))
+	for _, e := range q.entries {
+		totals[e.target]++
+		positions[e] = totals[e.target]
+	}
+	q.mu.Unlock()
+
+	for e, position := range positions {
+		_ = e.s.Conn().WritePacket(&packet.SetTitle{
+			ActionType: packet.TitleActionSetActionBar,
+			Text:       text.Colourf("<yellow>Queued for %s: position <bold>%d</bold> of %d</yellow>", e.target, position, totals[e.target]),
+		})
+	}
+}
+
+// priority returns the priority tier of the player with the identity passed.
+func (q *Queue) priority(identity login.IdentityData) int {
+	if p, ok := q.priorities[strings.ToLower(identity.DisplayName)]; ok {
+		return p
+	}
+	if identity.XUID != "" {
+		return q.priorities[identity.XUID]
+	}
+	return 0
+}
+
+// sort sorts the entries in the queue by their priority tier, and by the time they joined the queue within a tier.
+func (q *Queue) sort() {
+	sort.SliceStable(q.entries, func(i, j int) bool {
+		if q.entries[i].priority != q.entries[j].priority {
+			return q.entries[i].priority > q.entries[j].priority
+		}
+		return q.entries[i].joined.Before(q.entries[j].joined)
+	})
+}
+
+// refreshTemplate stores the game data of a backend server that one of the sessions on the proxy is connected to, so
+// that it can be used as a template for the waiting world.
+func (q *Queue) refreshTemplate() {
+	if q.world == nil {
+		return
+	}
+	for _, s := range q.store.All() {
+		if s.Server() == q.parking {
+			continue
+		}
+		if conn := s.ServerConn(); conn != nil {
+			q.templateMu.Lock()
+			q.tmpl, q.hasTmpl = conn.GameData(), true
+			q.templateMu.Unlock()
+			return
+		}
+	}
+}
+
+// template returns the latest game data stored by refreshTemplate, and false if none has been stored yet.
+func (q *Queue) template() (minecraft.GameData, bool) {
+	q.templateMu.Lock()
+	defer q.templateMu.Unlock()
+	return q.tmpl, q.hasTmpl
+}
@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	q := &Queue{priorities: map[string]int{"steve": 2, "2535400000000001": 5, "alex": 1}}
+	tests := []struct {
+		name     string
+		identity login.IdentityData
+		want     int
+	}{
+		{name: "name", identity: login.IdentityData{DisplayName: "Steve"}, want: 2},
+		{name: "xuid", identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000001"}, want: 5},
+		{name: "name before xuid", identity: login.IdentityData{DisplayName: "Alex", XUID: "2535400000000001"}, want: 1},
+		{name: "unknown", identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000002"}, want: 0},
+		{name: "offline", identity: login.IdentityData{DisplayName: "Notch"}, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := q.priority(test.identity); got != test.want {
+				t.Errorf("priority() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		entries []*entry
+		want    []int
+	}{
+		{
+			name:    "join order",
+			entries: []*entry{{joined: now.Add(time.Second * 2)}, {joined: now}, {joined: now.Add(time.Second)}},
+			want:    []int{1, 2, 0},
+		},
+		{
+			name: "priority tiers",
+			entries: []*entry{
+				{priority: 0, joined: now},
+				{priority: 2, joined: now.Add(time.Second * 3)},
+				{priority: 1, joined: now.Add(time.Second)},
+				{priority: 2, joined: now.Add(time.Second * 2)},
+			},
+			want: []int{3, 1, 2, 0},
+		},
+		{
+			name:    "equal join times keep their order",
+			entries: []*entry{{priority: 1, joined: now}, {priority: 1, joined: now}, {priority: 3, joined: now}},
+			want:    []int{2, 0, 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := &Queue{entries: append([]*entry(nil), test.entries...)}
+			q.sort()
+			for i, e := range q.entries {
+				if e != test.entries[test.want[i]] {
+					t.Fatalf("entry at position %d is entry %d, want entry %d", i, index(test.entries, e), test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	a, b, c, d, other := &session.Session{}, &session.Session{}, &session.Session{}, &session.Session{}, &session.Session{}
+	q := &Queue{entries: []*entry{
+		{s: a, target: Target{Server: "lobby"}},
+		{s: b, target: Target{Group: "survival"}},
+		{s: c, target: Target{Server: "lobby"}},
+		{s: d, target: Target{}},
+	}}
+	tests := []struct {
+		name            string
+		s               *session.Session
+		position, total int
+	}{
+		{name: "first for server", s: a, position: 1, total: 2},
+		{name: "second for server", s: c, position: 2, total: 2},
+		{name: "only for group", s: b, position: 1, total: 1},
+		{name: "only for network", s: d, position: 1, total: 1},
+		{name: "not queued", s: other, position: 0, total: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position, total := q.Position(test.s)
+			if position != test.position || total != test.total {
+				t.Errorf("Position() = %d of %d, want %d of %d", position, total, test.position, test.total)
+			}
+		})
+	}
+
+	if !q.Dequeue(a) || q.Dequeue(a) {
+		t.Fatalf("Dequeue() should only remove a queued session once")
+	}
+	if position, total := q.Position(c); position != 1 || total != 1 {
+		t.Errorf("Position() after dequeue = %d of %d, want 1 of 1", position, total)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	srv := server.New("lobby", "127.0.0.1:19133")
+	srv.SetMaxPlayers(3)
+	srv.IncrementPlayerCount()
+
+	bypass := &session.Session{}
+	bypass.SetBypassCapacity(true)
+	entries := []*entry{{s: &session.Session{}}, {s: &session.Session{}}, {s: &session.Session{}}, {s: bypass}}
+	q := &Queue{entries: append([]*entry(nil), entries...)}
+
+	tests := []struct {
+		name string
+		e    *entry
+		want bool
+	}{
+		{name: "first free slot", e: entries[0], want: true},
+		{name: "second free slot", e: entries[1], want: true},
+		{name: "slots held by moving entries", e: entries[2], want: false},
+		{name: "bypasses capacity", e: entries[3], want: true},
+		{name: "not queued", e: &entry{s: &session.Session{}}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := q.reserve(test.e, srv); got != test.want {
+				t.Errorf("reserve() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// index returns the index of the entry passed in the entries passed, or -1 if it is not in them.
+func index(entries []*entry, e *entry) int {
+	for i, v := range entries {
+		if v == e {
+			return i
+		}
+	}
+	return -1
+}
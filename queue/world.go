@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"time"
+)
+
+// waitingWorld is a minimal server hosted by the proxy on the loopback interface, which queued players are parked
+// in when no queue server is configured. Players are spawned as spectators in an empty world and everything they
+// send is ignored.
+type waitingWorld struct {
+	log      logging.Logger
+	listener *minecraft.Listener
+	template func() (minecraft.GameData, bool)
+}
+
+// newWaitingWorld starts a new waiting world on a random port of the loopback interface. The template function is
+// used to get the game data of a backend server, so that the item and block registries sent to players match those
+// of the server they are moved to afterwards.
+func newWaitingWorld(template func() (minecraft.GameData, bool), log logging.Logger) (*waitingWorld, error) {
+	l, err := minecraft.ListenConfig{AuthenticationDisabled: true}.Listen("raknet", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	w := &waitingWorld{log: log, listener: l, template: template}
+	go w.accept()
+	return w, nil
+}
+
+// Addr returns the address the waiting world is listening on.
+func (w *waitingWorld) Addr() string {
+	return w.listener.Addr().String()
+}
+
+// Close closes the waiting world.
+func (w *waitingWorld) Close() error {
+	return w.listener.Close()
+}
+
+// accept accepts connections to the waiting world until it is closed.
+func (w *waitingWorld) accept() {
+	for {
+		c, err := w.listener.Accept()
+		if err != nil {
+			return
+		}
+		go w.handle(c.(*minecraft.Conn))
+	}
+}
+
+// handle spawns the connection in the waiting world and discards all packets it sends until it is closed.
+func (w *waitingWorld) handle(conn *minecraft.Conn) {
+	defer conn.Close()
+
+	data, ok := w.template()
+	if !ok {
+		w.log.Errorf("waiting world has no backend server to copy game data from, item and block data may not match")
+	}
+	data.EntityUniqueID, data.EntityRuntimeID = 1, 1
+	data.Dimension = packet.DimensionOverworld
+	data.PlayerPosition = mgl32.Vec3{0, 128, 0}
+	data.WorldSpawn = protocol.BlockPos{0, 128, 0}
+	data.PlayerGameMode = packet.GameTypeSpectator
+	if data.BaseGameVersion == "" {
+		data.BaseGameVersion = protocol.CurrentVersion
+	}
+	if err := conn.StartGameTimeout(data, time.Minute); err != nil {
+		w.log.Errorf("failed to spawn connection in waiting world: %v", err)
+		return
+	}
+
+	_ = conn.WritePacket(&packet.NetworkChunkPublisherUpdate{Position: protocol.BlockPos{0, 128, 0}, Radius: 16})
+	for x := int32(-1); x <= 1; x++ {
+		for z := int32(-1); z <= 1; z++ {
+			_ = conn.WritePacket(&packet.LevelChunk{
+				Position:      protocol.ChunkPos{x, z},
+				Dimension:     packet.DimensionOverworld,
+				SubChunkCount: 1,
+				RawPayload:    session.EmptyChunk(packet.DimensionOverworld),
+			})
+		}
+	}
+
+	for {
+		if _, err := conn.ReadPacket(); err != nil {
+			return
+		}
+	}
+}
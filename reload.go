@@ -0,0 +1,277 @@
+package portal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paroxity/portal/session"
+	"github.com/sirupsen/logrus"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigReloader re-reads the configuration file of the proxy and applies the settings that may change while the
+// proxy is running. Reloads may be triggered by calling Reload, for example from a signal handler or an API, or by
+// watching the file for changes using Watch.
+type ConfigReloader struct {
+	p        *Portal
+	path     string
+	dir      string
+	override func(c *Config)
+
+	mu      sync.Mutex
+	conf    Config
+	modTime time.Time
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewConfigReloader creates a reloader for the configuration file at the path passed. Relative paths in the
+// configuration are resolved against the directory dir, in the same way as for LoadConfig. The configuration passed
+// is the one the proxy is currently running with, and is used to find the settings that changed.
+func NewConfigReloader(p *Portal, path, dir string, conf Config) *ConfigReloader {
+	r := &ConfigReloader{p: p, path: path, dir: dir, conf: conf, closed: make(chan struct{})}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return r
+}
+
+// Config returns the configuration that was applied last.
+func (r *ConfigReloader) Config() Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conf
+}
+
+// Override sets a function that is called with every configuration re-read by Reload before it is applied. It may be
+// used to keep settings that were overridden when the proxy started, such as by command line flags.
+func (r *ConfigReloader) Override(f func(c *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.override = f
+}
+
+// Reload re-reads the configuration file and applies it. See Apply for the settings that are applied.
+func (r *ConfigReloader) Reload() (restart []string, err error) {
+	c, err := LoadConfig(r.path, r.dir, r.p.Logger())
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	override := r.override
+	r.mu.Unlock()
+	if override != nil {
+		override(&c)
+	}
+	return r.Apply(c)
+}
+
+// Apply applies the settings of the configuration passed that may change while the proxy is running: the MOTD, log
+// level, whitelist, maintenance messages and bypass list, player latency reporting interval and socket secret. The
+// names of the settings that changed but only take effect after a restart are returned. Settings that could not be
+// applied are returned as an error, and keep their previous value.
+func (r *ConfigReloader) Apply(c Config) (restart []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, old := r.p, r.conf
+	var errs []error
+	if c.Network.MOTD != old.Network.MOTD {
+		if err := p.SetMOTD(c.Network.MOTD); err != nil {
+			errs = append(errs, err)
+			c.Network.MOTD = old.Network.MOTD
+		}
+	}
+	if c.Logger.Level != old.Logger.Level {
+		if err := r.applyLogLevel(c.Logger.Level); err != nil {
+			errs = append(errs, err)
+			c.Logger.Level = old.Logger.Level
+		}
+	}
+	if err := r.applyWhitelist(old, c); err != nil {
+		errs = append(errs, err)
+		c.Whitelist = old.Whitelist
+	}
+
+	m := p.Maintenance()
+	m.SetMessage(c.Maintenance.Message)
+	m.SetMOTD(c.Maintenance.MOTD)
+	m.SetBypass(c.Maintenance.Bypass)
+
+	if c.PlayerLatency.UpdateInterval != old.PlayerLatency.UpdateInterval {
+		if s, ok := p.SocketServer().(interface{ SetPlayerLatencyInterval(time.Duration) }); ok && c.PlayerLatency.UpdateInterval > 0 {
+			s.SetPlayerLatencyInterval(time.Second * time.Duration(c.PlayerLatency.UpdateInterval))
+		} else {
+			errs = append(errs, fmt.Errorf("player latency update interval cannot be changed"))
+			c.PlayerLatency.UpdateInterval = old.PlayerLatency.UpdateInterval
+		}
+	}
+	if c.Network.Communication.Secret != old.Network.Communication.Secret {
+		if s, ok := p.SocketServer().(interface{ SetSecret(string) }); ok {
+			s.SetSecret(c.Network.Communication.Secret)
+		} else {
+			errs = append(errs, fmt.Errorf("socket secret cannot be changed"))
+			c.Network.Communication.Secret = old.Network.Communication.Secret
+		}
+	}
+
+	restart = restartRequired(old, c)
+	if len(restart) > 0 {
+		p.Logger().Infof("the following settings changed but require a restart to take effect: %v", restart)
+	}
+	// The proxy keeps running with the old values of these settings, so they are kept to report them again on the
+	// next reload, until the proxy is restarted.
+	keepRestartSettings(old, &c)
+	r.conf = c
+	p.Logger().Infof("configuration has been reloaded")
+	return restart, errors.Join(errs...)
+}
+
+// applyLogLevel changes the level of the logger of the proxy, if it supports changing levels.
+func (r *ConfigReloader) applyLogLevel(v string) error {
+	level, err := logrus.ParseLevel(v)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", v, err)
+	}
+	l, ok := r.p.Logger().(interface{ SetLevel(logrus.Level) })
+	if !ok {
+		return fmt.Errorf("logger %T does not support changing the log level", r.p.Logger())
+	}
+	l.SetLevel(level)
+	return nil
+}
+
+// applyWhitelist applies the whitelist settings of the new configuration. Players that were added to the
+// configuration are added to the whitelist, and players that were removed from it are removed from the whitelist.
+// If the whitelist is enabled, online players that are no longer allowed to join are disconnected.
+func (r *ConfigReloader) applyWhitelist(old, c Config) error {
+	w, ok := r.p.Whitelist().(*session.PersistentWhitelist)
+	if !ok {
+		if reflect.DeepEqual(old.Whitelist, c.Whitelist) {
+			return nil
+		}
+		return fmt.Errorf("whitelist %T cannot be changed at runtime", r.p.Whitelist())
+	}
+	w.SetMessage(c.Whitelist.Message)
+
+	previous := make(map[string]struct{}, len(old.Whitelist.Players))
+	for _, name := range old.Whitelist.Players {
+		previous[strings.ToLower(name)] = struct{}{}
+	}
+	current := make(map[string]struct{}, len(c.Whitelist.Players))
+	for _, name := range c.Whitelist.Players {
+		current[strings.ToLower(name)] = struct{}{}
+		if _, ok := previous[strings.ToLower(name)]; !ok {
+			if err := w.Add(name, ""); err != nil {
+				return err
+			}
+		}
+	}
+	var removed bool
+	for _, name := range old.Whitelist.Players {
+		if _, ok := current[strings.ToLower(name)]; !ok {
+			if _, err := w.Remove(name); err != nil {
+				return err
+			}
+			removed = true
+		}
+	}
+	if c.Whitelist.Enabled != old.Whitelist.Enabled {
+		// Enabling the whitelist also disconnects the players that are not allowed to join.
+		return r.p.SetWhitelistEnabled(c.Whitelist.Enabled)
+	}
+	if removed && w.Enabled() {
+		r.p.EnforceWhitelist()
+	}
+	return nil
+}
+
+// Watch checks the configuration file for changes at the interval passed, and reloads it when it has been
+// modified. It blocks until the reloader is closed.
+func (r *ConfigReloader) Watch(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-r.closed:
+			return
+		}
+		info, err := os.Stat(r.path)
+		if err != nil || !info.ModTime().After(r.modTime) {
+			continue
+		}
+		r.modTime = info.ModTime()
+		if _, err := r.Reload(); err != nil {
+			r.p.Logger().Errorf("failed to reload configuration: %v", err)
+		}
+	}
+}
+
+// Close stops watching the configuration file for changes.
+func (r *ConfigReloader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+	return nil
+}
+
+// restartSetting is a setting of a configuration that only takes effect after the proxy is restarted.
+type restartSetting struct {
+	name string
+	// v is a pointer to the value of the setting in the configuration.
+	v any
+}
+
+// restartSettings returns the settings of the configuration passed that only take effect after the proxy is
+// restarted.
+func restartSettings(c *Config) []restartSetting {
+	return []restartSetting{
+		{"network.address", &c.Network.Address},
+		{"network.communication.address", &c.Network.Communication.Address},
+		{"network.reader_limits", &c.Network.ReaderLimits},
+		{"logger.file", &c.Logger.File},
+		{"player_latency.report", &c.PlayerLatency.Report},
+		{"servers", &c.Servers},
+		{"capacity", &c.Capacity},
+		{"load_balancer", &c.LoadBalancer},
+		{"health_check", &c.HealthCheck},
+		{"queue", &c.Queue},
+		{"fallback", &c.Fallback},
+		{"maintenance.file", &c.Maintenance.File},
+		{"bans", &c.Bans},
+		{"whitelist.file", &c.Whitelist.File},
+		// The listener copies its resource packs when it starts listening and cannot change them afterwards, and
+		// listening again would disconnect every player sharing its socket, so new packs require a restart.
+		{"resource_packs", &c.ResourcePacks},
+		{"metrics", &c.Metrics},
+		{"api", &c.API},
+		{"health", &c.Health},
+		{"shutdown", &c.Shutdown},
+	}
+}
+
+// restartRequired returns the names of the settings that differ between the two configurations passed and only
+// take effect after the proxy is restarted.
+func restartRequired(old, c Config) (changed []string) {
+	previous, current := restartSettings(&old), restartSettings(&c)
+	for i, s := range current {
+		if !reflect.DeepEqual(reflect.ValueOf(previous[i].v).Elem().Interface(), reflect.ValueOf(s.v).Elem().Interface()) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+// keepRestartSettings sets the settings of the configuration c that only take effect after a restart back to their
+// values in the configuration old, which the proxy is running with.
+func keepRestartSettings(old Config, c *Config) {
+	previous := restartSettings(&old)
+	for i, s := range restartSettings(c) {
+		reflect.ValueOf(s.v).Elem().Set(reflect.ValueOf(previous[i].v).Elem())
+	}
+}
@@ -0,0 +1,139 @@
+package portal
+
+import (
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/session"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sirupsen/logrus"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRestartRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "unchanged", modify: func(c *Config) {}},
+		{
+			name: "live settings",
+			modify: func(c *Config) {
+				c.Network.MOTD, c.Logger.Level, c.Network.Communication.Secret = "Hello", "debug", "secret"
+				c.Whitelist.Enabled, c.Whitelist.Players = true, []string{"Steve"}
+				c.Maintenance.Message, c.PlayerLatency.UpdateInterval = "Back soon", 10
+			},
+		},
+		{
+			name:   "network",
+			modify: func(c *Config) { c.Network.Address, c.Network.Communication.Address = ":19133", ":19134" },
+			want:   []string{"network.address", "network.communication.address"},
+		},
+		{
+			name: "sections",
+			modify: func(c *Config) {
+				c.Servers = []StaticServer{{Name: "lobby", Address: "127.0.0.1:19133"}}
+				c.LoadBalancer.Strategy, c.API.Enabled = "random", true
+			},
+			want: []string{"servers", "load_balancer", "api"},
+		},
+		{
+			name:   "files",
+			modify: func(c *Config) { c.Logger.File, c.Maintenance.File, c.Whitelist.File = "a.log", "m.json", "w.json" },
+			want:   []string{"logger.file", "maintenance.file", "whitelist.file"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			test.modify(&c)
+			if got := restartRequired(DefaultConfig(), c); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("restartRequired() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestKeepRestartSettings(t *testing.T) {
+	old := DefaultConfig()
+	c := DefaultConfig()
+	c.Network.Address, c.Queue.Enabled, c.Network.MOTD = ":19133", true, "Hello"
+
+	keepRestartSettings(old, &c)
+	if changed := restartRequired(old, c); len(changed) != 0 {
+		t.Errorf("settings %v were not kept", changed)
+	}
+	if c.Network.MOTD != "Hello" {
+		t.Errorf("network.motd = %q, want the new value %q", c.Network.MOTD, "Hello")
+	}
+}
+
+func TestApply(t *testing.T) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	w, err := session.NewPersistentWhitelist(filepath.Join(t.TempDir(), "whitelist.json"), false, []string{"Steve", "Alex"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(Options{
+		Logger:       logging.NewLogrus(l),
+		ListenConfig: minecraft.ListenConfig{StatusProvider: NewMOTDStatusProvider("Portal")},
+		Whitelist:    w,
+	})
+	conf := DefaultConfig()
+	conf.Whitelist.Players = []string{"Steve", "Alex"}
+	r := NewConfigReloader(p, "", "", conf)
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		restart []string
+		players []string
+	}{
+		{
+			name:    "restart setting",
+			modify:  func(c *Config) { c.Network.Address = ":19133" },
+			restart: []string{"network.address"},
+			players: []string{"Steve", "Alex"},
+		},
+		{
+			name:    "restart setting is reported until restart",
+			modify:  func(c *Config) { c.Network.Address, c.Network.MOTD = ":19133", "Hello" },
+			restart: []string{"network.address"},
+			players: []string{"Steve", "Alex"},
+		},
+		{
+			name:    "whitelisted players",
+			modify:  func(c *Config) { c.Network.Address, c.Whitelist.Players = ":19133", []string{"alex", "Notch"} },
+			restart: []string{"network.address"},
+			players: []string{"Alex", "Notch"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.Whitelist.Players = []string{"Steve", "Alex"}
+			test.modify(&c)
+
+			restart, err := r.Apply(c)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(restart, test.restart) {
+				t.Errorf("Apply() restart = %v, want %v", restart, test.restart)
+			}
+			if got := r.Config().Network.Address; got != conf.Network.Address {
+				t.Errorf("network.address = %q, want the running value %q", got, conf.Network.Address)
+			}
+			var players []string
+			for _, e := range w.Players() {
+				players = append(players, e.Name)
+			}
+			if !reflect.DeepEqual(players, test.players) {
+				t.Errorf("whitelisted players = %v, want %v", players, test.players)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"github.com/paroxity/portal/logging"
+	"github.com/sandertv/go-raknet"
+	"sync"
+	"time"
+)
+
+// HealthChecker periodically pings every server in a Registry with an unconnected RakNet ping. Servers that fail to
+// respond a set amount of times in a row are marked as unhealthy, which excludes them from load balancing and
+// transfers until they respond again.
+type HealthChecker struct {
+	log      logging.Logger
+	registry *Registry
+
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	failuresMu sync.Mutex
+	failures   map[*Server]int
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewHealthChecker creates a new health checker for the servers in the registry passed. Servers are pinged every
+// interval and must respond within the timeout. A server is marked as unhealthy after failing threshold checks in
+// a row, and as healthy again as soon as it responds.
+func NewHealthChecker(registry *Registry, interval, timeout time.Duration, threshold int, log logging.Logger) *HealthChecker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &HealthChecker{
+		log:      log,
+		registry: registry,
+
+		interval:  interval,
+		timeout:   timeout,
+		threshold: threshold,
+
+		failures: make(map[*Server]int),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Run checks the health of all servers at the interval of the health checker. It blocks until the health checker
+// is closed.
+func (h *HealthChecker) Run() {
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+	for {
+		servers := h.registry.Servers()
+		h.prune(servers)
+
+		var w sync.WaitGroup
+		for _, srv := range servers {
+			w.Add(1)
+			go func(srv *Server) {
+				defer w.Done()
+				h.Check(srv)
+			}(srv)
+		}
+		w.Wait()
+
+		select {
+		case <-t.C:
+		case <-h.closed:
+			return
+		}
+	}
+}
+
+// Check pings the server passed once and updates its latency and health accordingly.
+func (h *HealthChecker) Check(srv *Server) {
+	start := time.Now()
+	_, err := raknet.PingTimeout(srv.Address(), h.timeout)
+
+	h.failuresMu.Lock()
+	defer h.failuresMu.Unlock()
+	if err != nil {
+		h.failures[srv]++
+		if h.failures[srv] >= h.threshold && srv.Healthy() {
+			srv.SetHealthy(false)
+			h.log.Errorf("server %s is unhealthy and has been removed from load balancing: %v", srv.Name(), err)
+		}
+		return
+	}
+	delete(h.failures, srv)
+
+	srv.SetLatency(time.Since(start))
+	if !srv.Healthy() {
+		srv.SetHealthy(true)
+		h.log.Infof("server %s has recovered and has been added back to load balancing", srv.Name())
+	}
+}
+
+// prune forgets the failures of servers that are no longer in the registry.
+func (h *HealthChecker) prune(servers []*Server) {
+	h.failuresMu.Lock()
+	defer h.failuresMu.Unlock()
+
+	for srv := range h.failures {
+		found := false
+		for _, s := range servers {
+			if s == srv {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(h.failures, srv)
+		}
+	}
+}
+
+// Close stops the health checker.
+func (h *HealthChecker) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.closed)
+	})
+	return nil
+}
@@ -9,11 +9,58 @@ import (
 type Registry struct {
 	mu      sync.Mutex
 	servers map[string]*Server
+
+	hMutex   sync.RWMutex
+	h        RegistryHandler
+	monitors []RegistryHandler
+}
+
+// RegistryHandler handles servers being added to and removed from a Registry.
+type RegistryHandler interface {
+	// HandleServerAdd handles a server being added to the registry.
+	HandleServerAdd(srv *Server)
+	// HandleServerRemove handles a server being removed from the registry.
+	HandleServerRemove(srv *Server)
 }
 
+// NopRegistryHandler represents a RegistryHandler that does nothing.
+type NopRegistryHandler struct{}
+
+// HandleServerAdd ...
+func (NopRegistryHandler) HandleServerAdd(*Server) {}
+
+// HandleServerRemove ...
+func (NopRegistryHandler) HandleServerRemove(*Server) {}
+
 // NewDefaultRegistry creates a new Registry and returns it.
 func NewDefaultRegistry() *Registry {
-	return &Registry{servers: make(map[string]*Server)}
+	return &Registry{servers: make(map[string]*Server), h: NopRegistryHandler{}}
+}
+
+// Handle sets the handler of the registry, which is called when servers are added or removed. If nil is passed,
+// the handler is reset to a NopRegistryHandler.
+func (r *Registry) Handle(h RegistryHandler) {
+	if h == nil {
+		h = NopRegistryHandler{}
+	}
+	r.hMutex.Lock()
+	defer r.hMutex.Unlock()
+	r.h = h
+}
+
+// AddMonitor adds a handler that is called for every server added to or removed from the registry, after the
+// handler set using Handle. Unlike that handler, monitors are not replaced by calls to Handle.
+func (r *Registry) AddMonitor(h RegistryHandler) {
+	r.hMutex.Lock()
+	defer r.hMutex.Unlock()
+	r.monitors = append(r.monitors, h)
+}
+
+// handlers returns the handler of the registry followed by its monitors.
+func (r *Registry) handlers() []RegistryHandler {
+	r.hMutex.RLock()
+	defer r.hMutex.RUnlock()
+	return append([]RegistryHandler{r.h}, r.monitors...)
 }
 
 // Server attempts to find a server from its name, and returns the server and if it was found or not.
@@ -36,18 +83,89 @@ func (r *Registry) Servers() (all []*Server) {
 	return
 }
 
-// AddServer adds a server to the register.
-func (r *Registry) AddServer(srv *Server) {
+// ServersInGroup returns a slice of all the available servers on the proxy in the provided group.
+func (r *Registry) ServersInGroup(group string) (all []*Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, srv := range r.servers {
+		if strings.EqualFold(srv.Group(), group) {
+			all = append(all, srv)
+		}
+	}
+	return
+}
+
+// Groups returns the names of all the groups that at least one available server on the proxy is in.
+func (r *Registry) Groups() (groups []string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.servers[strings.ToLower(srv.Name())] = srv
+	seen := make(map[string]struct{})
+	for _, srv := range r.servers {
+		group := strings.ToLower(srv.Group())
+		if _, ok := seen[group]; ok || group == "" {
+			continue
+		}
+		seen[group] = struct{}{}
+		groups = append(groups, srv.Group())
+	}
+	return
+}
+
+// HealthyServers returns a slice of all the available servers on the proxy that are healthy.
+func (r *Registry) HealthyServers() (all []*Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, srv := range r.servers {
+		if srv.Healthy() {
+			all = append(all, srv)
+		}
+	}
+	return
+}
+
+// UnhealthyServers returns a slice of all the available servers on the proxy that are not healthy.
+func (r *Registry) UnhealthyServers() (all []*Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, srv := range r.servers {
+		if !srv.Healthy() {
+			all = append(all, srv)
+		}
+	}
+	return
+}
+
+// AddServer adds a server to the register. If a server with the same name was already registered, it is replaced.
+func (r *Registry) AddServer(srv *Server) {
+	r.mu.Lock()
+	name := strings.ToLower(srv.Name())
+	old, replaced := r.servers[name]
+	r.servers[name] = srv
+	r.mu.Unlock()
+
+	for _, h := range r.handlers() {
+		if replaced && old != srv {
+			h.HandleServerRemove(old)
+		}
+		h.HandleServerAdd(srv)
+	}
 }
 
 // RemoveServer removes a server from the register.
 func (r *Registry) RemoveServer(srv *Server) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	name := strings.ToLower(srv.Name())
+	removed, ok := r.servers[name]
+	delete(r.servers, name)
+	r.mu.Unlock()
 
-	delete(r.servers, strings.ToLower(srv.Name()))
+	if ok {
+		for _, h := range r.handlers() {
+			h.HandleServerRemove(removed)
+		}
+	}
 }
@@ -2,37 +2,121 @@ package server
 
 import (
 	"go.uber.org/atomic"
+	"time"
 )
 
 // Server represents a server connected to the proxy which players can join and play on.
 type Server struct {
 	name    string
+	group   string
 	address string
+	static  bool
+
+	weight     atomic.Int64
+	maxPlayers atomic.Int64
 
 	playerCount atomic.Int64
+	unhealthy   atomic.Bool
+	latency     atomic.Duration
 }
 
-// New creates a new Server with the provided name, group and address.
+// New creates a new Server with the provided name and address. The server is not part of any group.
 func New(name, address string) *Server {
+	return NewInGroup(name, "", address)
+}
+
+// NewInGroup creates a new Server with the provided name, group and address.
+func NewInGroup(name, group, address string) *Server {
 	s := &Server{
 		name:    name,
+		group:   group,
 		address: address,
 	}
+	s.weight.Store(1)
 
 	return s
 }
 
+// NewStatic creates a new static Server with the provided name, group and address. Static servers are configured
+// on the proxy itself rather than registered over the socket, and are never removed when a socket client with the
+// same name disconnects.
+func NewStatic(name, group, address string) *Server {
+	s := NewInGroup(name, group, address)
+	s.static = true
+	return s
+}
+
 // Name returns the name the server was registered with.
 func (s *Server) Name() string {
 	return s.name
 }
 
+// Group returns the name of the group the server was registered in. Servers in the same group can be addressed
+// collectively, for example to split players across several lobby servers.
+func (s *Server) Group() string {
+	return s.group
+}
+
 // Address returns the IP address the server was registered with. This should also contain the port separated
 // by a colon. E.g. "127.0.0.1:19132".
 func (s *Server) Address() string {
 	return s.address
 }
 
+// Static returns if the server was configured on the proxy itself rather than registered over the socket.
+func (s *Server) Static() bool {
+	return s.static
+}
+
+// Weight returns the weight of the server, which is used by load balancers to send more players to servers with
+// a higher weight. The default weight is 1.
+func (s *Server) Weight() int {
+	return int(s.weight.Load())
+}
+
+// SetWeight sets the weight of the server.
+func (s *Server) SetWeight(weight int) {
+	s.weight.Store(int64(weight))
+}
+
+// MaxPlayers returns the maximum amount of players that may be connected to the server. If zero, the amount of
+// players is not limited.
+func (s *Server) MaxPlayers() int {
+	return int(s.maxPlayers.Load())
+}
+
+// SetMaxPlayers sets the maximum amount of players that may be connected to the server. Zero removes the limit.
+func (s *Server) SetMaxPlayers(maxPlayers int) {
+	s.maxPlayers.Store(int64(maxPlayers))
+}
+
+// Full returns if the server has reached its maximum amount of players.
+func (s *Server) Full() bool {
+	maxPlayers := s.MaxPlayers()
+	return maxPlayers > 0 && s.PlayerCount() >= maxPlayers
+}
+
+// Healthy returns if the server is healthy. Servers that are not healthy are skipped by load balancers.
+func (s *Server) Healthy() bool {
+	return !s.unhealthy.Load()
+}
+
+// SetHealthy marks the server as healthy or unhealthy.
+func (s *Server) SetHealthy(v bool) {
+	s.unhealthy.Store(!v)
+}
+
+// Latency returns the round-trip latency between the proxy and the server, as measured by the last successful
+// health check. It is zero if the server has not been checked yet.
+func (s *Server) Latency() time.Duration {
+	return s.latency.Load()
+}
+
+// SetLatency sets the round-trip latency between the proxy and the server.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.latency.Store(latency)
+}
+
 // IncrementPlayerCount increments the player count of the server.
 func (s *Server) IncrementPlayerCount() {
 	s.playerCount.Add(1)
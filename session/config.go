@@ -0,0 +1,31 @@
+package session
+
+import (
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+)
+
+// Config holds the settings used to create a new Session using NewWithConfig.
+type Config struct {
+	// Store is the store which holds the session while it is open.
+	Store *Store
+	// LoadBalancer is used to find the server the session connects to when it first joins.
+	LoadBalancer LoadBalancer
+	// Server is the server the session connects to when it first joins. If it is nil, the LoadBalancer is used
+	// to find a server instead.
+	Server *server.Server
+	// Group is the group the LoadBalancer finds a server in when the session first joins. If it is empty, the
+	// LoadBalancer may pick a server from any group. It is ignored if Server is set.
+	Group string
+	// Fallback is used to find a server for the session when the server it is connected to closes the
+	// connection. If it is nil, the session is closed instead.
+	Fallback LoadBalancer
+	// Log is the logger used by the session.
+	Log logging.Logger
+	// Monitors are called with the new session to create handlers that observe every event of the session. These
+	// handlers are called after all other handlers, even for cancelled events, and unlike handlers added through
+	// Handle or AddHandler they are never removed.
+	Monitors []func(s *Session) Handler
+	// BypassCapacity is if the session may join servers that have reached their maximum amount of players.
+	BypassCapacity bool
+}
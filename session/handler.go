@@ -4,9 +4,13 @@ import (
 	"github.com/paroxity/portal/event"
 	"github.com/paroxity/portal/server"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"time"
 )
 
-// Handler handles events that are called by a player's session.
+// Handler handles events that are called by a player's session. HandleServerBoundPacket, HandleChat and HandleCommand
+// are called by the goroutine that reads packets from the client, so they must not call Session.TransferContext,
+// which waits for that goroutine to receive the acknowledgement of the client. Session.Transfer may be called instead,
+// or TransferContext may be called in a new goroutine.
 type Handler interface {
 	// HandleClientBoundPacket handles a packet that's sent by the session's connected server. ctx.Cancel()
 	// may be called to cancel the packet.
@@ -14,19 +18,169 @@ type Handler interface {
 	// HandleServerBoundPacket handles a packet that's sent by the session. ctx.Cancel() may be called to
 	// cancel the packet.
 	HandleServerBoundPacket(ctx *event.Context, pk packet.Packet)
+	// HandleChat handles a chat message sent by the session. ctx.Cancel() may be called to prevent the message
+	// from being sent to the server.
+	HandleChat(ctx *event.Context, message string)
+	// HandleCommand handles a command sent by the session. The command line includes the leading slash.
+	// ctx.Cancel() may be called to prevent the command from being sent to the server.
+	HandleCommand(ctx *event.Context, commandLine string)
+	// HandleServerConnect handles the session connecting and spawning on the first server it joins.
+	HandleServerConnect(srv *server.Server)
 	// HandleServerDisconnect handles the server connection getting closed. ctx.Cancel() may be called after
 	// transferring the player to cancel disconnecting them.
 	HandleServerDisconnect(ctx *event.Context, err error)
 	// HandleTransfer handles a session being transferred to another server. ctx.Cancel() may be called to
 	// cancel the transfer.
 	HandleTransfer(ctx *event.Context, svr *server.Server)
+	// HandleTransferComplete handles a session that has finished transferring from one server to another.
+	HandleTransferComplete(from, to *server.Server)
+	// HandleTransferFail handles a transfer of the session to the server passed that has failed with the error
+	// passed.
+	HandleTransferFail(srv *server.Server, err error)
+	// HandleLatencyUpdate handles the latency between the session and the proxy being measured. It is called at a
+	// fixed interval, even if the latency did not change since it was last measured.
+	HandleLatencyUpdate(latency time.Duration)
 	// HandleQuit handles the closing of a session. It is always called when the session is disconnected,
 	// regardless of the reason.
 	HandleQuit()
 }
 
-// NopHandler implements the Handler interface but does not execute any code when an event is called. The
-// default handler of sessions is set to NopHandler.
+// Priority represents the priority of a Handler added to a session. Handlers with a higher priority are called
+// before handlers with a lower priority.
+type Priority int
+
+const (
+	PriorityLowest Priority = iota
+	PriorityLow
+	PriorityNormal
+	PriorityHigh
+	PriorityHighest
+	// PriorityMonitor is the priority of handlers that only observe events. They are called after all other
+	// handlers, even if the event was cancelled, and should not change the outcome of the event.
+	PriorityMonitor
+)
+
+// HandlerToken is returned when a handler is added to a session, and may be used to remove it again.
+type HandlerToken struct {
+	id uint64
+}
+
+// handlerEntry is a handler added to a session along with its priority.
+type handlerEntry struct {
+	h        Handler
+	priority Priority
+	token    HandlerToken
+}
+
+// handlerChain is an immutable list of handlers, ordered by the order in which they should be called. It
+// implements Handler by calling each handler in the chain.
+type handlerChain []handlerEntry
+
+// Compile time check to make sure handlerChain implements Handler.
+var _ Handler = handlerChain(nil)
+
+// with returns a copy of the chain with the entry passed added in the right position. Monitor handlers are placed
+// last, and handlers with an equal priority are called in the order they were added.
+func (c handlerChain) with(e handlerEntry) handlerChain {
+	n := make(handlerChain, 0, len(c)+1)
+	added := false
+	for _, v := range c {
+		if !added && e.before(v) {
+			n, added = append(n, e), true
+		}
+		n = append(n, v)
+	}
+	if !added {
+		n = append(n, e)
+	}
+	return n
+}
+
+// without returns a copy of the chain with the handler with the token passed removed, and if it was found.
+func (c handlerChain) without(token HandlerToken) (handlerChain, bool) {
+	n := make(handlerChain, 0, len(c))
+	for _, v := range c {
+		if v.token != token {
+			n = append(n, v)
+		}
+	}
+	return n, len(n) != len(c)
+}
+
+// before returns if the entry should be called before the other entry passed.
+func (e handlerEntry) before(other handlerEntry) bool {
+	if e.priority == PriorityMonitor || other.priority == PriorityMonitor {
+		return other.priority == PriorityMonitor && e.priority != PriorityMonitor
+	}
+	return e.priority > other.priority
+}
+
+// each calls f for every handler in the chain. Once the context is cancelled, only monitor handlers are called.
+func (c handlerChain) each(ctx *event.Context, f func(h Handler)) {
+	for _, e := range c {
+		if ctx != nil && ctx.Cancelled() && e.priority != PriorityMonitor {
+			continue
+		}
+		f(e.h)
+	}
+}
+
+// HandleClientBoundPacket ...
+func (c handlerChain) HandleClientBoundPacket(ctx *event.Context, pk packet.Packet) {
+	c.each(ctx, func(h Handler) { h.HandleClientBoundPacket(ctx, pk) })
+}
+
+// HandleServerBoundPacket ...
+func (c handlerChain) HandleServerBoundPacket(ctx *event.Context, pk packet.Packet) {
+	c.each(ctx, func(h Handler) { h.HandleServerBoundPacket(ctx, pk) })
+}
+
+// HandleChat ...
+func (c handlerChain) HandleChat(ctx *event.Context, message string) {
+	c.each(ctx, func(h Handler) { h.HandleChat(ctx, message) })
+}
+
+// HandleCommand ...
+func (c handlerChain) HandleCommand(ctx *event.Context, commandLine string) {
+	c.each(ctx, func(h Handler) { h.HandleCommand(ctx, commandLine) })
+}
+
+// HandleServerConnect ...
+func (c handlerChain) HandleServerConnect(srv *server.Server) {
+	c.each(nil, func(h Handler) { h.HandleServerConnect(srv) })
+}
+
+// HandleServerDisconnect ...
+func (c handlerChain) HandleServerDisconnect(ctx *event.Context, err error) {
+	c.each(ctx, func(h Handler) { h.HandleServerDisconnect(ctx, err) })
+}
+
+// HandleTransfer ...
+func (c handlerChain) HandleTransfer(ctx *event.Context, srv *server.Server) {
+	c.each(ctx, func(h Handler) { h.HandleTransfer(ctx, srv) })
+}
+
+// HandleTransferComplete ...
+func (c handlerChain) HandleTransferComplete(from, to *server.Server) {
+	c.each(nil, func(h Handler) { h.HandleTransferComplete(from, to) })
+}
+
+// HandleTransferFail ...
+func (c handlerChain) HandleTransferFail(srv *server.Server, err error) {
+	c.each(nil, func(h Handler) { h.HandleTransferFail(srv, err) })
+}
+
+// HandleLatencyUpdate ...
+func (c handlerChain) HandleLatencyUpdate(latency time.Duration) {
+	c.each(nil, func(h Handler) { h.HandleLatencyUpdate(latency) })
+}
+
+// HandleQuit ...
+func (c handlerChain) HandleQuit() {
+	c.each(nil, func(h Handler) { h.HandleQuit() })
+}
+
+// NopHandler implements the Handler interface but does not execute any code when an event is called.
 // Users may embed NopHandler to avoid having to implement each method.
 type NopHandler struct{}
 
@@ -39,11 +193,29 @@ func (NopHandler) HandleClientBoundPacket(*event.Context, packet.Packet) {}
 // HandleServerBoundPacket ...
 func (NopHandler) HandleServerBoundPacket(*event.Context, packet.Packet) {}
 
+// HandleChat ...
+func (NopHandler) HandleChat(*event.Context, string) {}
+
+// HandleCommand ...
+func (NopHandler) HandleCommand(*event.Context, string) {}
+
+// HandleServerConnect ...
+func (NopHandler) HandleServerConnect(*server.Server) {}
+
 // HandleServerDisconnect ...
 func (NopHandler) HandleServerDisconnect(*event.Context, error) {}
 
 // HandleTransfer ...
 func (NopHandler) HandleTransfer(*event.Context, *server.Server) {}
 
+// HandleTransferComplete ...
+func (NopHandler) HandleTransferComplete(*server.Server, *server.Server) {}
+
+// HandleTransferFail ...
+func (NopHandler) HandleTransferFail(*server.Server, error) {}
+
+// HandleLatencyUpdate ...
+func (NopHandler) HandleLatencyUpdate(time.Duration) {}
+
 // HandleQuit ...
 func (NopHandler) HandleQuit() {}
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"github.com/paroxity/portal/event"
+	"reflect"
+	"testing"
+)
+
+// recordingHandler is a Handler that records its name when HandleChat is called, and cancels the event if cancel
+// is true.
+type recordingHandler struct {
+	NopHandler
+	name   string
+	cancel bool
+	calls  *[]string
+}
+
+// HandleChat ...
+func (h recordingHandler) HandleChat(ctx *event.Context, _ string) {
+	*h.calls = append(*h.calls, h.name)
+	if h.cancel {
+		ctx.Cancel()
+	}
+}
+
+func TestHandlerChainWith(t *testing.T) {
+	type add struct {
+		name     string
+		priority Priority
+	}
+	tests := []struct {
+		name string
+		adds []add
+		want []string
+	}{
+		{
+			name: "priorities",
+			adds: []add{{"normal", PriorityNormal}, {"highest", PriorityHighest}, {"lowest", PriorityLowest}, {"high", PriorityHigh}},
+			want: []string{"highest", "high", "normal", "lowest"},
+		},
+		{
+			name: "equal priorities keep their order",
+			adds: []add{{"a", PriorityNormal}, {"b", PriorityNormal}, {"c", PriorityHigh}, {"d", PriorityNormal}},
+			want: []string{"c", "a", "b", "d"},
+		},
+		{
+			name: "monitors are last",
+			adds: []add{{"monitor a", PriorityMonitor}, {"lowest", PriorityLowest}, {"monitor b", PriorityMonitor}, {"highest", PriorityHighest}},
+			want: []string{"highest", "lowest", "monitor a", "monitor b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			var c handlerChain
+			for i, a := range test.adds {
+				c = c.with(handlerEntry{
+					h:        recordingHandler{name: a.name, calls: &calls},
+					priority: a.priority,
+					token:    HandlerToken{id: uint64(i)},
+				})
+			}
+			c.HandleChat(event.C(), "")
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("handlers were called in the order %v, want %v", calls, test.want)
+			}
+		})
+	}
+}
+
+func TestHandlerChainWithout(t *testing.T) {
+	var calls []string
+	var c handlerChain
+	for i, name := range []string{"a", "b", "c"} {
+		c = c.with(handlerEntry{h: recordingHandler{name: name, calls: &calls}, priority: PriorityNormal, token: HandlerToken{id: uint64(i)}})
+	}
+
+	tests := []struct {
+		name  string
+		token HandlerToken
+		found bool
+		want  []string
+	}{
+		{name: "first", token: HandlerToken{id: 0}, found: true, want: []string{"b", "c"}},
+		{name: "middle", token: HandlerToken{id: 1}, found: true, want: []string{"a", "c"}},
+		{name: "unknown", token: HandlerToken{id: 5}, found: false, want: []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls = nil
+			n, found := c.without(test.token)
+			if found != test.found {
+				t.Errorf("without() found = %v, want %v", found, test.found)
+			}
+			n.HandleChat(event.C(), "")
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("handlers left are %v, want %v", calls, test.want)
+			}
+			if len(c) != 3 {
+				t.Errorf("without() modified the original chain")
+			}
+		})
+	}
+}
+
+func TestHandlerChainEach(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel string
+		want   []string
+	}{
+		{name: "not cancelled", want: []string{"high", "normal", "low", "monitor"}},
+		{name: "cancelled", cancel: "normal", want: []string{"high", "normal", "monitor"}},
+		{name: "cancelled by first", cancel: "high", want: []string{"high", "monitor"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			var c handlerChain
+			for i, e := range []struct {
+				name     string
+				priority Priority
+			}{{"low", PriorityLow}, {"monitor", PriorityMonitor}, {"normal", PriorityNormal}, {"high", PriorityHigh}} {
+				c = c.with(handlerEntry{
+					h:        recordingHandler{name: e.name, cancel: e.name == test.cancel, calls: &calls},
+					priority: e.priority,
+					token:    HandlerToken{id: uint64(i)},
+				})
+			}
+			ctx := event.C()
+			c.HandleChat(ctx, "")
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("handlers called were %v, want %v", calls, test.want)
+			}
+			if ctx.Cancelled() != (test.cancel != "") {
+				t.Errorf("context cancelled = %v, want %v", ctx.Cancelled(), test.cancel != "")
+			}
+		})
+	}
+}
@@ -2,15 +2,47 @@ package session
 
 import (
 	"github.com/paroxity/portal/server"
+	"strings"
 )
 
 // LoadBalancer represents a load balancer which helps balance the load of players on the proxy.
 type LoadBalancer interface {
-	// FindServer finds a server for the session to connect to when they first join. If nil is returned, the
-	// player is kicked from the proxy.
+	// FindServer finds a server for the session to connect to when they first join, or when the server they
+	// are connected to closes the connection. The server the session is currently connected to, if any, should
+	// not be returned. If nil is returned, the player is kicked from the proxy.
 	FindServer(session *Session) *server.Server
 }
 
+// GroupLoadBalancer may be implemented by a LoadBalancer to find servers in a specific group. All load balancers
+// in this package implement it.
+type GroupLoadBalancer interface {
+	LoadBalancer
+	// FindServerInGroup finds the best server in the provided group for the session to connect to. The server
+	// the session is currently connected to, if any, should not be returned. If nil is returned, there is no
+	// server in the group available for the session.
+	FindServerInGroup(session *Session, group string) *server.Server
+}
+
+// FindServerInGroup finds a server in the provided group for the session to connect to using the load balancer
+// passed. If the load balancer does not implement GroupLoadBalancer, the server found by FindServer is returned
+// only if it is in the group.
+func FindServerInGroup(loadBalancer LoadBalancer, session *Session, group string) *server.Server {
+	if b, ok := loadBalancer.(GroupLoadBalancer); ok {
+		return b.FindServerInGroup(session, group)
+	}
+	if srv := loadBalancer.FindServer(session); srv != nil && strings.EqualFold(srv.Group(), group) {
+		return srv
+	}
+	return nil
+}
+
+// Recorder may be implemented by a LoadBalancer to be told which server a session was connected to when the
+// session is closed.
+type Recorder interface {
+	// Record records the server the session was last connected to. It is called when the session is closed.
+	Record(session *Session, srv *server.Server)
+}
+
 // SplitLoadBalancer attempts to split players evenly across all the servers.
 type SplitLoadBalancer struct {
 	registry *server.Registry
@@ -22,11 +54,64 @@ func NewSplitLoadBalancer(registry *server.Registry) *SplitLoadBalancer {
 }
 
 // FindServer ...
-func (b *SplitLoadBalancer) FindServer(*Session) (srv *server.Server) {
-	for _, s := range b.registry.Servers() {
+func (b *SplitLoadBalancer) FindServer(session *Session) *server.Server {
+	return b.split(session, b.registry.Servers())
+}
+
+// FindServerInGroup ...
+func (b *SplitLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return b.split(session, b.registry.ServersInGroup(group))
+}
+
+// split returns the available server with the least players out of the servers provided.
+func (b *SplitLoadBalancer) split(session *Session, servers []*server.Server) (srv *server.Server) {
+	for _, s := range available(session, servers) {
 		if srv == nil || srv.PlayerCount() > s.PlayerCount() {
 			srv = s
 		}
 	}
 	return srv
 }
+
+// available filters the servers passed and returns only the servers that the session may be sent to. Servers that
+// are unhealthy, full servers unless the session may bypass capacity limits, and the server the session is currently
+// connected to, are not available.
+func available(session *Session, servers []*server.Server) []*server.Server {
+	current := session.Server()
+	all := make([]*server.Server, 0, len(servers))
+	for _, srv := range servers {
+		if srv == current || !srv.Healthy() || (srv.Full() && !session.BypassesCapacity()) {
+			continue
+		}
+		all = append(all, srv)
+	}
+	return all
+}
+
+// FallbackLoadBalancer always picks a fixed fallback server if it is available, and uses another load balancer to
+// find a server otherwise.
+type FallbackLoadBalancer struct {
+	registry     *server.Registry
+	name         string
+	loadBalancer LoadBalancer
+}
+
+// NewFallbackLoadBalancer creates a "fallback" load balancer which prefers the server with the provided name, and
+// falls back to the provided load balancer if that server is unavailable. The name may be empty to always use the
+// load balancer.
+func NewFallbackLoadBalancer(registry *server.Registry, name string, loadBalancer LoadBalancer) *FallbackLoadBalancer {
+	return &FallbackLoadBalancer{registry: registry, name: name, loadBalancer: loadBalancer}
+}
+
+// FindServer ...
+func (b *FallbackLoadBalancer) FindServer(session *Session) *server.Server {
+	if srv, ok := b.registry.Server(b.name); ok && len(available(session, []*server.Server{srv})) == 1 {
+		return srv
+	}
+	return b.loadBalancer.FindServer(session)
+}
+
+// FindServerInGroup ...
+func (b *FallbackLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return FindServerInGroup(b.loadBalancer, session, group)
+}
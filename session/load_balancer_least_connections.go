@@ -0,0 +1,34 @@
+package session
+
+import "github.com/paroxity/portal/server"
+
+// LeastConnectionsLoadBalancer sends players to the available server with the least players. Servers that have
+// reached their maximum amount of players are skipped, unless the player may bypass it.
+type LeastConnectionsLoadBalancer struct {
+	registry *server.Registry
+}
+
+// NewLeastConnectionsLoadBalancer creates a "least connections" load balancer with the provided server registry.
+func NewLeastConnectionsLoadBalancer(registry *server.Registry) *LeastConnectionsLoadBalancer {
+	return &LeastConnectionsLoadBalancer{registry: registry}
+}
+
+// FindServer ...
+func (b *LeastConnectionsLoadBalancer) FindServer(session *Session) *server.Server {
+	return b.least(session, b.registry.Servers())
+}
+
+// FindServerInGroup ...
+func (b *LeastConnectionsLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return b.least(session, b.registry.ServersInGroup(group))
+}
+
+// least returns the available server with the least players out of the servers provided.
+func (b *LeastConnectionsLoadBalancer) least(session *Session, servers []*server.Server) (srv *server.Server) {
+	for _, s := range available(session, servers) {
+		if srv == nil || srv.PlayerCount() > s.PlayerCount() {
+			srv = s
+		}
+	}
+	return srv
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"github.com/paroxity/portal/server"
+	"math/rand"
+)
+
+// RandomLoadBalancer sends each player to a random available server.
+type RandomLoadBalancer struct {
+	registry *server.Registry
+}
+
+// NewRandomLoadBalancer creates a "random" load balancer with the provided server registry.
+func NewRandomLoadBalancer(registry *server.Registry) *RandomLoadBalancer {
+	return &RandomLoadBalancer{registry: registry}
+}
+
+// FindServer ...
+func (b *RandomLoadBalancer) FindServer(session *Session) *server.Server {
+	return b.pick(session, b.registry.Servers())
+}
+
+// FindServerInGroup ...
+func (b *RandomLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return b.pick(session, b.registry.ServersInGroup(group))
+}
+
+// pick returns a random available server out of the servers provided.
+func (b *RandomLoadBalancer) pick(session *Session, servers []*server.Server) *server.Server {
+	servers = available(session, servers)
+	if len(servers) == 0 {
+		return nil
+	}
+	return servers[rand.Intn(len(servers))]
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"github.com/paroxity/portal/server"
+	"go.uber.org/atomic"
+	"sort"
+)
+
+// RoundRobinLoadBalancer sends each player to the next server in turn, cycling through all the available servers.
+type RoundRobinLoadBalancer struct {
+	registry *server.Registry
+	next     atomic.Uint64
+}
+
+// NewRoundRobinLoadBalancer creates a "round-robin" load balancer with the provided server registry.
+func NewRoundRobinLoadBalancer(registry *server.Registry) *RoundRobinLoadBalancer {
+	return &RoundRobinLoadBalancer{registry: registry}
+}
+
+// FindServer ...
+func (b *RoundRobinLoadBalancer) FindServer(session *Session) *server.Server {
+	return b.cycle(session, b.registry.Servers())
+}
+
+// FindServerInGroup ...
+func (b *RoundRobinLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return b.cycle(session, b.registry.ServersInGroup(group))
+}
+
+// cycle returns the next available server out of the servers provided.
+func (b *RoundRobinLoadBalancer) cycle(session *Session, servers []*server.Server) *server.Server {
+	servers = available(session, servers)
+	if len(servers) == 0 {
+		return nil
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name() < servers[j].Name()
+	})
+	return servers[(b.next.Inc()-1)%uint64(len(servers))]
+}
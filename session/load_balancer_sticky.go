@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// stickySaveDelay is the time the sticky load balancer waits after a player quit before saving the last servers
+	// of players, so that players quitting shortly after each other only cause the file to be written once.
+	stickySaveDelay = time.Second * 10
+	// stickyExpiry is the time after which the last server of a player is forgotten if they have not played since.
+	stickyExpiry = time.Hour * 24 * 30
+)
+
+// stickyEntry holds the server a player last played on and when they quit it.
+type stickyEntry struct {
+	Server   string    `json:"server"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// StickyLoadBalancer sends players back to the server they last played on if it is available, and uses another
+// load balancer to find a server otherwise. The server each player last played on is persisted to a file so that it
+// is remembered across restarts, and is forgotten if the player has not played for 30 days.
+type StickyLoadBalancer struct {
+	registry     *server.Registry
+	loadBalancer LoadBalancer
+	log          logging.Logger
+
+	path    string
+	mu      sync.Mutex
+	servers map[uuid.UUID]stickyEntry
+	save    *time.Timer
+}
+
+// NewStickyLoadBalancer creates a "sticky" load balancer with the provided server registry. The last servers of
+// players are loaded from and saved to the file at the path passed. If a player has not played before, or if their
+// last server is unavailable, the provided load balancer is used instead. Changes are saved shortly after players
+// quit, and Close must be called to save any pending changes when the load balancer is no longer used.
+func NewStickyLoadBalancer(registry *server.Registry, path string, loadBalancer LoadBalancer, log logging.Logger) (*StickyLoadBalancer, error) {
+	b := &StickyLoadBalancer{
+		registry:     registry,
+		loadBalancer: loadBalancer,
+		log:          log,
+
+		path:    path,
+		servers: make(map[uuid.UUID]stickyEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.servers); err != nil {
+		return nil, err
+	}
+	b.expire()
+	return b, nil
+}
+
+// FindServer ...
+func (b *StickyLoadBalancer) FindServer(session *Session) *server.Server {
+	if srv, ok := b.last(session); ok {
+		return srv
+	}
+	return b.loadBalancer.FindServer(session)
+}
+
+// FindServerInGroup ...
+func (b *StickyLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	if srv, ok := b.last(session); ok && strings.EqualFold(srv.Group(), group) {
+		return srv
+	}
+	return FindServerInGroup(b.loadBalancer, session, group)
+}
+
+// Record ...
+func (b *StickyLoadBalancer) Record(session *Session, srv *server.Server) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.servers[session.UUID()] = stickyEntry{Server: srv.Name(), LastSeen: time.Now()}
+	if b.save == nil {
+		b.save = time.AfterFunc(stickySaveDelay, func() {
+			if err := b.flush(); err != nil {
+				b.log.Errorf("failed to save sticky servers: %v", err)
+			}
+		})
+	}
+}
+
+// Close stops the pending save of the load balancer, if any, and saves the last servers of players to the file
+// immediately.
+func (b *StickyLoadBalancer) Close() error {
+	return b.flush()
+}
+
+// flush saves the last servers of players that have played in the last 30 days to the file.
+func (b *StickyLoadBalancer) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.save == nil {
+		// Nothing changed since the last save.
+		return nil
+	}
+	b.save.Stop()
+	b.save = nil
+
+	b.expire()
+	data, err := json.MarshalIndent(b.servers, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, data, 0644)
+}
+
+// expire removes the last servers of players that have not played in the last 30 days. The mutex must be held or the
+// load balancer must not yet be in use when it is called.
+func (b *StickyLoadBalancer) expire() {
+	for id, entry := range b.servers {
+		if time.Since(entry.LastSeen) > stickyExpiry {
+			delete(b.servers, id)
+		}
+	}
+}
+
+// last returns the server the session last played on, if it is still available.
+func (b *StickyLoadBalancer) last(session *Session) (*server.Server, bool) {
+	b.mu.Lock()
+	entry, ok := b.servers[session.UUID()]
+	b.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	srv, ok := b.registry.Server(entry.Server)
+	if !ok || len(available(session, []*server.Server{srv})) == 0 {
+		return nil, false
+	}
+	return srv, true
+}
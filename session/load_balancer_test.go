@@ -0,0 +1,289 @@
+package session
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/server"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testServer describes a server registered for a load balancer test.
+type testServer struct {
+	name, group           string
+	players, maxPlayers   int
+	weight                int
+	unhealthy, zeroWeight bool
+}
+
+// newTestRegistry returns a registry with the servers described by the test servers passed.
+func newTestRegistry(servers ...testServer) *server.Registry {
+	r := server.NewDefaultRegistry()
+	for _, s := range servers {
+		srv := server.NewInGroup(s.name, s.group, "127.0.0.1:19133")
+		for i := 0; i < s.players; i++ {
+			srv.IncrementPlayerCount()
+		}
+		srv.SetMaxPlayers(s.maxPlayers)
+		if s.weight != 0 || s.zeroWeight {
+			srv.SetWeight(s.weight)
+		}
+		srv.SetHealthy(!s.unhealthy)
+		r.AddServer(srv)
+	}
+	return r
+}
+
+// newTestSession returns a session connected to the server with the name passed in the registry passed, if any.
+func newTestSession(r *server.Registry, current string, bypass bool) *Session {
+	s := &Session{uuid: uuid.New()}
+	s.server, _ = r.Server(current)
+	s.SetBypassCapacity(bypass)
+	return s
+}
+
+// serverName returns the name of the server passed, or an empty string if it is nil.
+func serverName(srv *server.Server) string {
+	if srv == nil {
+		return ""
+	}
+	return srv.Name()
+}
+
+func TestFewestPlayersLoadBalancers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []testServer
+		current string
+		group   string
+		bypass  bool
+		want    string
+	}{
+		{
+			name:    "fewest players",
+			servers: []testServer{{name: "a", players: 3}, {name: "b", players: 1}, {name: "c", players: 2}},
+			want:    "b",
+		},
+		{
+			name:    "current server is skipped",
+			servers: []testServer{{name: "a", players: 3}, {name: "b", players: 1}},
+			current: "b",
+			want:    "a",
+		},
+		{
+			name:    "unhealthy server is skipped",
+			servers: []testServer{{name: "a", players: 3}, {name: "b", players: 1, unhealthy: true}},
+			want:    "a",
+		},
+		{
+			name:    "full server is skipped",
+			servers: []testServer{{name: "a", players: 3}, {name: "b", players: 1, maxPlayers: 1}},
+			want:    "a",
+		},
+		{
+			name:    "full server with bypass",
+			servers: []testServer{{name: "a", players: 3}, {name: "b", players: 1, maxPlayers: 1}},
+			bypass:  true,
+			want:    "b",
+		},
+		{
+			name:    "group",
+			servers: []testServer{{name: "a", group: "lobby", players: 3}, {name: "b", players: 1}, {name: "c", group: "Lobby", players: 2}},
+			group:   "lobby",
+			want:    "c",
+		},
+		{
+			name:    "nothing available",
+			servers: []testServer{{name: "a", players: 1, maxPlayers: 1}, {name: "b", unhealthy: true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRegistry(test.servers...)
+			s := newTestSession(r, test.current, test.bypass)
+			for _, b := range []GroupLoadBalancer{NewSplitLoadBalancer(r), NewLeastConnectionsLoadBalancer(r)} {
+				var srv *server.Server
+				if test.group != "" {
+					srv = b.FindServerInGroup(s, test.group)
+				} else {
+					srv = b.FindServer(s)
+				}
+				if serverName(srv) != test.want {
+					t.Errorf("%T found server %q, want %q", b, serverName(srv), test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundRobinLoadBalancer(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []testServer
+		current string
+		want    []string
+	}{
+		{name: "cycles by name", servers: []testServer{{name: "c"}, {name: "a"}, {name: "b"}}, want: []string{"a", "b", "c", "a"}},
+		{name: "skips unavailable", servers: []testServer{{name: "a"}, {name: "b", unhealthy: true}, {name: "c"}}, current: "c", want: []string{"a", "a"}},
+		{name: "nothing available", servers: []testServer{{name: "a", unhealthy: true}}, want: []string{""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRegistry(test.servers...)
+			s := newTestSession(r, test.current, false)
+			b := NewRoundRobinLoadBalancer(r)
+			for i, want := range test.want {
+				if got := serverName(b.FindServer(s)); got != want {
+					t.Errorf("server %d is %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRandomLoadBalancers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []testServer
+		// weighted is true if the test only applies to the weighted load balancer.
+		weighted bool
+		want     map[string]bool
+	}{
+		{
+			name:    "available servers",
+			servers: []testServer{{name: "a"}, {name: "b"}, {name: "c", unhealthy: true}},
+			want:    map[string]bool{"a": true, "b": true},
+		},
+		{
+			name:     "weight of zero is never picked",
+			servers:  []testServer{{name: "a", weight: 3}, {name: "b", zeroWeight: true}, {name: "c", weight: -1}},
+			weighted: true,
+			want:     map[string]bool{"a": true},
+		},
+		{
+			name:    "nothing available",
+			servers: []testServer{{name: "a", unhealthy: true}},
+			want:    map[string]bool{"": true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRegistry(test.servers...)
+			s := newTestSession(r, "", false)
+			balancers := []LoadBalancer{NewWeightedLoadBalancer(r)}
+			if !test.weighted {
+				balancers = append(balancers, NewRandomLoadBalancer(r))
+			}
+			for _, b := range balancers {
+				for i := 0; i < 50; i++ {
+					if got := serverName(b.FindServer(s)); !test.want[got] {
+						t.Fatalf("%T found server %q, want one of %v", b, got, test.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStickyLoadBalancer(t *testing.T) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	r := newTestRegistry(testServer{name: "a", group: "lobby", players: 2}, testServer{name: "b", group: "lobby"}, testServer{name: "c", group: "survival", players: 5})
+	steve, alex, notch, herobrine := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	path := filepath.Join(t.TempDir(), "sticky.json")
+	data, _ := json.Marshal(map[uuid.UUID]stickyEntry{
+		steve: {Server: "c", LastSeen: time.Now().Add(-time.Hour)},
+		alex:  {Server: "c", LastSeen: time.Now().Add(-stickyExpiry - time.Hour)},
+		notch: {Server: "d", LastSeen: time.Now()},
+	})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewStickyLoadBalancer(r, path, NewSplitLoadBalancer(r), logging.NewLogrus(l))
+	if err != nil {
+		t.Fatalf("NewStickyLoadBalancer() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		id    uuid.UUID
+		group string
+		want  string
+	}{
+		{name: "last server", id: steve, want: "c"},
+		{name: "last server in other group", id: steve, group: "lobby", want: "b"},
+		{name: "expired", id: alex, want: "b"},
+		{name: "last server removed", id: notch, want: "b"},
+		{name: "new player", id: herobrine, want: "b"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Session{uuid: test.id}
+			var srv *server.Server
+			if test.group != "" {
+				srv = b.FindServerInGroup(s, test.group)
+			} else {
+				srv = b.FindServer(s)
+			}
+			if serverName(srv) != test.want {
+				t.Errorf("found server %q, want %q", serverName(srv), test.want)
+			}
+		})
+	}
+
+	a, _ := r.Server("a")
+	b.Record(&Session{uuid: herobrine}, a)
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	b, err = NewStickyLoadBalancer(r, path, NewSplitLoadBalancer(r), logging.NewLogrus(l))
+	if err != nil {
+		t.Fatalf("NewStickyLoadBalancer() error = %v", err)
+	}
+	if srv := b.FindServer(&Session{uuid: herobrine}); serverName(srv) != "a" {
+		t.Errorf("recorded server was not saved: found %q, want %q", serverName(srv), "a")
+	}
+	if _, ok := b.servers[alex]; ok {
+		t.Errorf("expired entry was saved")
+	}
+}
+
+// serverLoadBalancer is a LoadBalancer that always returns the same server and does not implement
+// GroupLoadBalancer.
+type serverLoadBalancer struct {
+	srv *server.Server
+}
+
+// FindServer ...
+func (b serverLoadBalancer) FindServer(*Session) *server.Server {
+	return b.srv
+}
+
+func TestFindServerInGroup(t *testing.T) {
+	r := newTestRegistry(testServer{name: "a", group: "lobby", players: 1}, testServer{name: "b", group: "lobby"})
+	a, _ := r.Server("a")
+	tests := []struct {
+		name  string
+		b     LoadBalancer
+		group string
+		want  string
+	}{
+		{name: "group load balancer", b: NewSplitLoadBalancer(r), group: "lobby", want: "b"},
+		{name: "server in group", b: serverLoadBalancer{srv: a}, group: "LOBBY", want: "a"},
+		{name: "server in other group", b: serverLoadBalancer{srv: a}, group: "survival"},
+		{name: "no server", b: serverLoadBalancer{}, group: "lobby"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if srv := FindServerInGroup(test.b, newTestSession(r, "", false), test.group); serverName(srv) != test.want {
+				t.Errorf("FindServerInGroup() = %q, want %q", serverName(srv), test.want)
+			}
+		})
+	}
+}
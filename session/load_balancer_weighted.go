@@ -0,0 +1,57 @@
+package session
+
+import (
+	"github.com/paroxity/portal/server"
+	"math/rand"
+)
+
+// WeightedLoadBalancer sends players to a random available server, where servers with a higher weight are picked
+// more often than servers with a lower weight.
+type WeightedLoadBalancer struct {
+	registry *server.Registry
+}
+
+// NewWeightedLoadBalancer creates a "weighted" load balancer with the provided server registry. The weight of each
+// server is the weight set on the server, and servers with a weight of 0 or less are never picked.
+func NewWeightedLoadBalancer(registry *server.Registry) *WeightedLoadBalancer {
+	return &WeightedLoadBalancer{registry: registry}
+}
+
+// FindServer ...
+func (b *WeightedLoadBalancer) FindServer(session *Session) *server.Server {
+	return b.pick(session, b.registry.Servers())
+}
+
+// FindServerInGroup ...
+func (b *WeightedLoadBalancer) FindServerInGroup(session *Session, group string) *server.Server {
+	return b.pick(session, b.registry.ServersInGroup(group))
+}
+
+// pick returns a random available server out of the servers provided, taking the weight of each server into
+// account.
+func (b *WeightedLoadBalancer) pick(session *Session, servers []*server.Server) *server.Server {
+	servers = available(session, servers)
+
+	var total int
+	for _, srv := range servers {
+		if w := srv.Weight(); w > 0 {
+			total += w
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for _, srv := range servers {
+		w := srv.Weight()
+		if w <= 0 {
+			continue
+		}
+		if n < w {
+			return srv
+		}
+		n -= w
+	}
+	return nil
+}
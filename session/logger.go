@@ -0,0 +1,49 @@
+package session
+
+import (
+	"github.com/paroxity/portal/logging"
+)
+
+// sessionLogger is the logger of a session. Every line it logs carries the name of the server the session is
+// connected to at the time of logging, on top of the fields of the logger it wraps.
+type sessionLogger struct {
+	s *Session
+	l logging.Logger
+}
+
+// Debugf ...
+func (l sessionLogger) Debugf(format string, v ...any) {
+	l.current().Debugf(format, v...)
+}
+
+// Infof ...
+func (l sessionLogger) Infof(format string, v ...any) {
+	l.current().Infof(format, v...)
+}
+
+// Errorf ...
+func (l sessionLogger) Errorf(format string, v ...any) {
+	l.current().Errorf(format, v...)
+}
+
+// Fatalf ...
+func (l sessionLogger) Fatalf(format string, v ...any) {
+	l.current().Fatalf(format, v...)
+}
+
+// With ...
+func (l sessionLogger) With(args ...any) logging.Logger {
+	return sessionLogger{s: l.s, l: logging.With(l.l, args...)}
+}
+
+// current returns the wrapped logger with the server the session is currently connected to added. Unlike
+// Session.Server, it does not wait for the session to log in, so that it may be used while logging in.
+func (l sessionLogger) current() logging.Logger {
+	l.s.serverMu.RLock()
+	srv := l.s.server
+	l.s.serverMu.RUnlock()
+	if srv == nil {
+		return l.l
+	}
+	return logging.With(l.l, "server", srv.Name())
+}
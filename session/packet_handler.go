@@ -7,9 +7,12 @@ import (
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 	"net"
-	"sync"
+	"time"
 )
 
+// latencyUpdateInterval is the interval at which the latency of a session is measured.
+const latencyUpdateInterval = time.Second * 5
+
 // handlePackets handles the packets sent between the client and the server. Processes such as runtime
 // translations are also handled here.
 func handlePackets(s *Session) {
@@ -30,53 +33,7 @@ func handlePackets(s *Session) {
 				pk.XUID = ""
 			case *packet.PlayerAction:
 				if pk.ActionType == protocol.PlayerActionDimensionChangeDone {
-					if s.transferring.Load() {
-						s.serverMu.Lock()
-						gameData := s.tempServerConn.GameData()
-						s.changeDimension(packet.DimensionOverworld, gameData.PlayerPosition)
-
-						var w sync.WaitGroup
-						w.Add(2)
-						go func() {
-							s.clearEntities()
-							s.clearEffects()
-							w.Done()
-						}()
-						go func() {
-							s.clearPlayerList()
-							s.clearBossBars()
-							s.clearScoreboard()
-							w.Done()
-						}()
-
-						_ = s.conn.WritePacket(&packet.MovePlayer{
-							EntityRuntimeID: s.originalRuntimeID,
-							Position:        gameData.PlayerPosition,
-							Pitch:           gameData.Pitch,
-							Yaw:             gameData.Yaw,
-							Mode:            packet.MoveModeReset,
-						})
-
-						_ = s.conn.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopRaining, EventData: 10000})
-						_ = s.conn.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopThunderstorm})
-						_ = s.conn.WritePacket(&packet.SetDifficulty{Difficulty: uint32(gameData.Difficulty)})
-						_ = s.conn.WritePacket(&packet.GameRulesChanged{GameRules: gameData.GameRules})
-						_ = s.conn.WritePacket(&packet.SetPlayerGameType{GameType: gameData.PlayerGameMode})
-
-						w.Wait()
-
-						_ = s.serverConn.Close()
-
-						s.serverConn = s.tempServerConn
-						s.tempServerConn = nil
-						s.serverMu.Unlock()
-
-						s.updateTranslatorData(gameData)
-
-						s.transferring.Store(false)
-						s.postTransfer.Store(true)
-
-						s.log.Infof("%s finished transferring to %s", s.Conn().IdentityData().DisplayName, s.Server().Name())
+					if s.transferring.Load() && s.completeTransfer() {
 						continue
 					} else if s.postTransfer.CAS(true, false) {
 						continue
@@ -91,6 +48,14 @@ func handlePackets(s *Session) {
 			}
 
 			ctx := event.C()
+			switch pk := pk.(type) {
+			case *packet.Text:
+				if pk.TextType == packet.TextTypeChat {
+					s.handler().HandleChat(ctx, pk.Message)
+				}
+			case *packet.CommandRequest:
+				s.handler().HandleCommand(ctx, pk.CommandLine)
+			}
 			s.handler().HandleServerBoundPacket(ctx, pk)
 
 			ctx.Continue(func() {
@@ -99,6 +64,20 @@ func handlePackets(s *Session) {
 		}
 	}()
 
+	go func() {
+		t := time.NewTicker(latencyUpdateInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+			case <-s.closing:
+				return
+			}
+			s.handler().HandleLatencyUpdate(s.conn.Latency())
+		}
+	}()
+
 	go func() {
 		for {
 			conn := s.ServerConn()
@@ -107,11 +86,18 @@ func handlePackets(s *Session) {
 				if conn != s.ServerConn() {
 					continue
 				}
+				if s.Transferring() {
+					s.waitForTransfer()
+					continue
+				}
 				ctx := event.C()
 				s.handler().HandleServerDisconnect(ctx, err)
 
 				c := false
 				ctx.Continue(func() {
+					if s.fallbackFrom(err) {
+						return
+					}
 					c = true
 					if disconnect, ok := errors.Unwrap(err).(minecraft.DisconnectError); ok {
 						s.log.Debugf(disconnect.Error())
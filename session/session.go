@@ -1,20 +1,23 @@
 package session
 
 import (
+	"context"
 	"errors"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/google/uuid"
-	"github.com/paroxity/portal/event"
-	"github.com/paroxity/portal/internal"
+	"github.com/paroxity/portal/logging"
 	"github.com/paroxity/portal/server"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sandertv/gophertunnel/minecraft/text"
 	"github.com/scylladb/go-set/b16set"
 	"github.com/scylladb/go-set/i32set"
 	"github.com/scylladb/go-set/i64set"
 	"github.com/scylladb/go-set/strset"
 	"go.uber.org/atomic"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -23,13 +26,17 @@ import (
 type Session struct {
 	*translator
 
-	log   internal.Logger
-	conn  *minecraft.Conn
-	store *Store
+	log      logging.Logger
+	conn     *minecraft.Conn
+	store    *Store
+	balancer LoadBalancer
+	fallback LoadBalancer
 
 	hMutex sync.RWMutex
-	// h holds the current handler of the session.
-	h Handler
+	// h holds the current handlers of the session, ordered by the order in which they are called.
+	h         handlerChain
+	hTokenSeq uint64
+	monitors  handlerChain
 
 	loginMu        sync.RWMutex
 	serverMu       sync.RWMutex
@@ -45,17 +52,31 @@ type Session struct {
 
 	uuid uuid.UUID
 
+	bypassCapacity atomic.Bool
+
+	transferMu   sync.Mutex
+	transferDone chan struct{}
 	transferring atomic.Bool
 	postTransfer atomic.Bool
+	closed       atomic.Bool
+	closing      chan struct{}
 	once         sync.Once
 }
 
-// New creates a new Session with the provided connection.
-func New(conn *minecraft.Conn, store *Store, loadBalancer LoadBalancer, log internal.Logger) (s *Session, err error) {
+// New creates a new Session with the provided connection, which first joins the server found by the load balancer
+// passed. It is the same as calling NewWithConfig with only the store, load balancer and logger set.
+func New(conn *minecraft.Conn, store *Store, loadBalancer LoadBalancer, log logging.Logger) (*Session, error) {
+	return NewWithConfig(conn, Config{Store: store, LoadBalancer: loadBalancer, Log: log})
+}
+
+// NewWithConfig creates a new Session with the provided connection, using the settings in the Config passed.
+func NewWithConfig(conn *minecraft.Conn, conf Config) (s *Session, err error) {
+	store := conf.Store
 	s = &Session{
-		log:   log,
-		conn:  conn,
-		store: store,
+		conn:     conn,
+		store:    store,
+		balancer: conf.LoadBalancer,
+		fallback: conf.Fallback,
 
 		entities:    i64set.New(),
 		playerList:  b16set.New(),
@@ -63,9 +84,11 @@ func New(conn *minecraft.Conn, store *Store, loadBalancer LoadBalancer, log inte
 		bossBars:    i64set.New(),
 		scoreboards: strset.New(),
 
-		h:    NopHandler{},
-		uuid: uuid.MustParse(conn.IdentityData().Identity),
+		uuid:    uuid.MustParse(conn.IdentityData().Identity),
+		closing: make(chan struct{}),
 	}
+	identity := conn.IdentityData()
+	s.log = sessionLogger{s: s, l: logging.With(conf.Log, "player", identity.DisplayName, "uuid", identity.Identity)}
 
 	store.Store(s)
 	defer func() {
@@ -74,45 +97,59 @@ func New(conn *minecraft.Conn, store *Store, loadBalancer LoadBalancer, log inte
 		}
 	}()
 
-	srv := loadBalancer.FindServer(s)
+	s.bypassCapacity.Store(conf.BypassCapacity)
+	for _, f := range conf.Monitors {
+		s.monitors = append(s.monitors, handlerEntry{h: f(s), priority: PriorityMonitor})
+	}
+	s.Handle(nil)
+
+	srv := conf.Server
+	if srv == nil && conf.Group != "" {
+		srv = FindServerInGroup(conf.LoadBalancer, s, conf.Group)
+	} else if srv == nil {
+		srv = conf.LoadBalancer.FindServer(s)
+	}
 	if srv == nil {
 		return s, errors.New("load balancer did not return a server for the player to join")
 	}
 	srv.IncrementPlayerCount()
+	s.serverMu.Lock()
 	s.server = srv
+	s.serverMu.Unlock()
 
 	s.loginMu.Lock()
 	go func() {
 		defer s.loginMu.Unlock()
-		srvConn, err := s.dial(srv)
+		srvConn, err := s.dial(context.Background(), srv)
 		if err != nil {
-			log.Errorf("failed to dial server %s: %w", srv.Address(), err)
+			s.log.Errorf("failed to dial server %s: %v", srv.Address(), err)
 			return
 		}
 
 		s.serverConn = srvConn
 		if err = s.login(); err != nil {
 			_ = srvConn.Close()
-			log.Errorf("failed to login to server %s: %w", srv.Address(), err)
+			s.log.Errorf("failed to login to server %s: %v", srv.Address(), err)
 			return
 		}
-		log.Infof("%s has been connected to server %s", conn.IdentityData().DisplayName, srv.Name())
+		s.log.Infof("%s has been connected to server %s", conn.IdentityData().DisplayName, srv.Name())
 
 		s.translator = newTranslator(srvConn.GameData())
+		s.handler().HandleServerConnect(srv)
 		handlePackets(s)
 	}()
 	return s, nil
 }
 
 // dial dials a new connection to the provided server. It then returns the connection between the proxy and
-// that server, along with any error that may have occurred.
-func (s *Session) dial(srv *server.Server) (*minecraft.Conn, error) {
+// that server, along with any error that may have occurred. The context passed may be used to cancel dialing.
+func (s *Session) dial(ctx context.Context, srv *server.Server) (*minecraft.Conn, error) {
 	i := s.conn.IdentityData()
 	i.XUID = ""
 	return minecraft.Dialer{
 		ClientData:   s.conn.ClientData(),
 		IdentityData: i,
-	}.Dial("raknet", srv.Address())
+	}.DialContext(ctx, "raknet", srv.Address())
 }
 
 // login performs the initial login sequence for the session.
@@ -152,6 +189,12 @@ func (s *Session) Server() *server.Server {
 	return s.server
 }
 
+// Logger returns the logger of the session. Every line it logs carries the name and UUID of the player, and the
+// name of the server the player is connected to.
+func (s *Session) Logger() logging.Logger {
+	return s.log
+}
+
 // ServerConn returns the connection for the session's current server.
 func (s *Session) ServerConn() *minecraft.Conn {
 	s.waitForLogin()
@@ -165,93 +208,91 @@ func (s *Session) UUID() uuid.UUID {
 	return s.uuid
 }
 
+// BypassesCapacity returns if the session may join servers that have reached their maximum amount of players.
+func (s *Session) BypassesCapacity() bool {
+	return s.bypassCapacity.Load()
+}
+
+// SetBypassCapacity sets if the session may join servers that have reached their maximum amount of players.
+func (s *Session) SetBypassCapacity(v bool) {
+	s.bypassCapacity.Store(v)
+}
+
 // Handle sets the handler for the current session which can be used to handle different events from the
-// session. If the handler is nil, a NopHandler is used instead.
+// session. All handlers previously added using Handle or AddHandler are removed, and h is added with
+// PriorityNormal. If the handler is nil, all handlers are removed. The monitors set in the Config of the session
+// are never removed.
 func (s *Session) Handle(h Handler) {
 	s.hMutex.Lock()
-	defer s.hMutex.Unlock()
+	s.h = s.monitors
+	s.hMutex.Unlock()
 
-	if h == nil {
-		h = NopHandler{}
+	if h != nil {
+		s.AddHandler(h, PriorityNormal)
 	}
-	s.h = h
 }
 
-// Transfer transfers the session to the provided server, returning any error that may have occurred during
-// the initial transfer.
-func (s *Session) Transfer(srv *server.Server) (err error) {
-	s.waitForLogin()
-	if !s.transferring.CAS(false, true) {
-		return errors.New("already being transferred")
-	}
-
-	s.log.Infof("%s is being transferred from %s to %s", s.conn.IdentityData().DisplayName, s.Server().Name(), srv.Name())
-
-	ctx := event.C()
-	s.handler().HandleTransfer(ctx, srv)
+// AddHandler adds a handler to the session with the priority passed, alongside any handlers already added.
+// Handlers with a higher priority are called first, and if one of them cancels an event, handlers with a lower
+// priority are not called for it. Handlers with PriorityMonitor are always called last, even for cancelled events.
+// The token returned may be passed to RemoveHandler to remove the handler again.
+func (s *Session) AddHandler(h Handler, priority Priority) HandlerToken {
+	s.hMutex.Lock()
+	defer s.hMutex.Unlock()
 
-	ctx.Continue(func() {
-		conn, err := s.dial(srv)
-		if err != nil {
-			return
-		}
-		if err = conn.DoSpawnTimeout(time.Minute); err != nil {
-			return
-		}
+	s.hTokenSeq++
+	token := HandlerToken{id: s.hTokenSeq}
+	s.h = s.h.with(handlerEntry{h: h, priority: priority, token: token})
+	return token
+}
 
-		s.serverMu.Lock()
-		s.tempServerConn = conn
-		s.serverMu.Unlock()
+// RemoveHandler removes the handler added with the token passed. It returns false if no handler was found for the
+// token.
+func (s *Session) RemoveHandler(token HandlerToken) bool {
+	if token == (HandlerToken{}) {
+		// The zero token belongs to the monitors of the session, which may not be removed.
+		return false
+	}
+	s.hMutex.Lock()
+	defer s.hMutex.Unlock()
 
-		var proxyDimension int32
-		for _, dimension := range []int32{packet.DimensionOverworld, packet.DimensionNether, packet.DimensionEnd} {
-			if dimension != s.serverConn.GameData().Dimension && dimension != conn.GameData().Dimension {
-				proxyDimension = dimension
-				break
-			}
-		}
+	h, ok := s.h.without(token)
+	s.h = h
+	return ok
+}
 
-		pos := s.conn.GameData().PlayerPosition
-		s.changeDimension(proxyDimension, pos)
-
-		chunkX := int32(pos.X()) >> 4
-		chunkZ := int32(pos.Z()) >> 4
-		for x := int32(-1); x <= 1; x++ {
-			for z := int32(-1); z <= 1; z++ {
-				_ = s.conn.WritePacket(&packet.LevelChunk{
-					Position:      protocol.ChunkPos{chunkX + x, chunkZ + z},
-					Dimension:     packet.DimensionNether,
-					SubChunkCount: 1,
-					RawPayload:    emptyChunk(proxyDimension),
-				})
-			}
-		}
+// fallbackFrom attempts to transfer the session to a fallback server after the server it was connected to closed
+// the connection with the error passed. The reason of the disconnection is shown to the player in chat. False is
+// returned if no fallback server could be found or if the transfer failed.
+func (s *Session) fallbackFrom(err error) bool {
+	if s.fallback == nil || s.closed.Load() {
+		return false
+	}
+	old := s.Server()
+	srv := s.fallback.FindServer(s)
+	if srv == nil || srv == old {
+		return false
+	}
 
-		s.serverMu.Lock()
-		s.server.DecrementPlayerCount()
-		s.server = srv
-		s.server.IncrementPlayerCount()
-		s.serverMu.Unlock()
-	})
+	reason := "Server closed"
+	var disconnect minecraft.DisconnectError
+	if errors.As(err, &disconnect) {
+		reason = disconnect.Error()
+	}
+	s.log.Infof("%s lost connection to %s (%s), falling back to %s", s.conn.IdentityData().DisplayName, old.Name(), reason, srv.Name())
 
-	ctx.Stop(func() {
-		s.setTransferring(false)
+	if err := s.Transfer(srv); err != nil {
+		s.log.Errorf("failed to transfer %s to fallback server %s: %v", s.conn.IdentityData().DisplayName, srv.Name(), err)
+		return false
+	}
+	_ = s.conn.WritePacket(&packet.Text{
+		TextType: packet.TextTypeRaw,
+		Message:  text.Colourf("<red>You were moved to %s: %s</red>", srv.Name(), reason),
 	})
-
-	return
-}
-
-// Transferring returns if the session is currently transferring to a different server or not.
-func (s *Session) Transferring() bool {
-	return s.transferring.Load()
+	return true
 }
 
-// setTransferring sets if the session is transferring to a different server.
-func (s *Session) setTransferring(v bool) {
-	s.transferring.Store(v)
-}
-
-// handler() returns the handler connected to the session.
+// handler() returns a handler which calls all the handlers of the session in order.
 func (s *Session) handler() Handler {
 	s.hMutex.RLock()
 	defer s.hMutex.RUnlock()
@@ -261,8 +302,11 @@ func (s *Session) handler() Handler {
 // Close closes the session and any linked connections/counters.
 func (s *Session) Close() {
 	s.once.Do(func() {
+		s.closed.Store(true)
+		close(s.closing)
 		s.handler().HandleQuit()
-		s.Handle(NopHandler{})
+		s.Handle(nil)
+		s.finishTransfer()
 
 		s.store.Delete(s.UUID())
 
@@ -276,6 +320,9 @@ func (s *Session) Close() {
 
 		if s.server != nil {
 			s.server.DecrementPlayerCount()
+			if r, ok := s.balancer.(Recorder); ok {
+				r.Record(s, s.server)
+			}
 		}
 	})
 }
@@ -290,6 +337,24 @@ func (s *Session) Disconnect(message string) {
 	s.Close()
 }
 
+// Redirect sends the session to the provided address, in the format of "address:port", using a transfer packet and
+// closes the session afterwards. Unlike Transfer, the player leaves the proxy entirely.
+func (s *Session) Redirect(address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return err
+	}
+	if err := s.conn.WritePacket(&packet.Transfer{Address: host, Port: uint16(port)}); err != nil {
+		return err
+	}
+	s.Close()
+	return nil
+}
+
 // clearEntities flushes the entities map and despawns the entities for the client.
 func (s *Session) clearEntities() {
 	s.entities.Each(func(id int64) bool {
@@ -0,0 +1,291 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paroxity/portal/event"
+	"github.com/paroxity/portal/server"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTransferInProgress is returned when a session is transferred while it is already being transferred.
+	ErrTransferInProgress = errors.New("already being transferred")
+	// ErrTransferCancelled is returned when a transfer is cancelled, either by a Handler or by cancelling the
+	// context passed to TransferContext.
+	ErrTransferCancelled = errors.New("transfer was cancelled")
+	// ErrTransferTimeout is returned when the deadline of the context passed to TransferContext is exceeded
+	// before the session has spawned on the new server.
+	ErrTransferTimeout = errors.New("transfer timed out")
+	// ErrServerUnhealthy is returned when a session is transferred to a server that is not healthy.
+	ErrServerUnhealthy = errors.New("server is unhealthy")
+	// ErrServerFull is returned when a session is transferred to a server that has reached its maximum amount of
+	// players, and the session may not bypass capacity limits.
+	ErrServerFull = errors.New("server is full")
+	// ErrSessionClosed is returned when a session is closed before or during a transfer.
+	ErrSessionClosed = errors.New("session closed")
+)
+
+// transferCompleteTimeout is the maximum time the proxy waits for the client to acknowledge the dimension change
+// at the end of a transfer. If the client does not respond in time, the transfer is completed regardless.
+const transferCompleteTimeout = time.Second * 15
+
+// Transfer transfers the session to the provided server. It blocks until the session has spawned on the new server
+// or the transfer has failed, and returns the reason of the failure, if any. Spawning on the new server must complete
+// within a minute. The transfer is completed in the background once the client acknowledges the dimension change,
+// after which HandleTransferComplete is called. Unlike TransferContext, Transfer may be called from a Handler.
+func (s *Session) Transfer(srv *server.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	done, old, err := s.beginTransfer(ctx, srv)
+	if err != nil {
+		return err
+	}
+	go func() {
+		_ = s.awaitTransfer(done, old, srv)
+	}()
+	return nil
+}
+
+// TransferContext transfers the session to the provided server. It blocks until the transfer has completed or
+// failed, and returns the reason of the failure, if any. The context passed bounds the time it may take to dial
+// and spawn on the new server: ErrTransferTimeout is returned if its deadline is exceeded, and
+// ErrTransferCancelled if it is cancelled. Once the session has spawned on the new server, the transfer can no
+// longer fail.
+//
+// The transfer is only completed once the client acknowledges the dimension change, which is handled by the
+// goroutine that reads packets from the client. TransferContext must therefore not be called from a Handler method
+// called by that goroutine, as the client would be frozen until the acknowledgement times out. Transfer should be
+// used there instead.
+func (s *Session) TransferContext(ctx context.Context, srv *server.Server) error {
+	done, old, err := s.beginTransfer(ctx, srv)
+	if err != nil {
+		return err
+	}
+	return s.awaitTransfer(done, old, srv)
+}
+
+// beginTransfer transfers the session to the provided server up to the point where it has spawned on the new server.
+// It returns a channel that is closed once the transfer has completed, along with the server the session was
+// transferred from, or the reason the transfer failed.
+func (s *Session) beginTransfer(ctx context.Context, srv *server.Server) (<-chan struct{}, *server.Server, error) {
+	s.waitForLogin()
+	if s.closed.Load() {
+		return nil, nil, ErrSessionClosed
+	}
+	if !srv.Healthy() {
+		err := fmt.Errorf("%w: %s", ErrServerUnhealthy, srv.Name())
+		s.handler().HandleTransferFail(srv, err)
+		return nil, nil, err
+	}
+	if srv.Full() && !s.BypassesCapacity() {
+		err := fmt.Errorf("%w: %s", ErrServerFull, srv.Name())
+		s.handler().HandleTransferFail(srv, err)
+		return nil, nil, err
+	}
+	done, ok := s.startTransfer()
+	if !ok {
+		return nil, nil, ErrTransferInProgress
+	}
+
+	old := s.Server()
+	s.log.Infof("%s is being transferred from %s to %s", s.conn.IdentityData().DisplayName, old.Name(), srv.Name())
+
+	var err error
+	ectx := event.C()
+	s.handler().HandleTransfer(ectx, srv)
+	ectx.Continue(func() {
+		err = s.transfer(ctx, srv)
+	})
+	ectx.Stop(func() {
+		err = ErrTransferCancelled
+	})
+	if err != nil {
+		s.finishTransfer()
+		s.handler().HandleTransferFail(srv, err)
+		return nil, nil, err
+	}
+	return done, old, nil
+}
+
+// awaitTransfer waits until the client has acknowledged the dimension change of the transfer from the server old to
+// the server srv, or completes the transfer if it does not do so in time.
+func (s *Session) awaitTransfer(done <-chan struct{}, old, srv *server.Server) error {
+	t := time.NewTimer(transferCompleteTimeout)
+	defer t.Stop()
+	select {
+	case <-done:
+	case <-t.C:
+		s.log.Debugf("%s did not acknowledge the dimension change in time, completing transfer", s.conn.IdentityData().DisplayName)
+		s.completeTransfer()
+	}
+	if s.closed.Load() {
+		return ErrSessionClosed
+	}
+	s.handler().HandleTransferComplete(old, srv)
+	return nil
+}
+
+// transfer dials the provided server and spawns the session on it, after which the client is moved to a temporary
+// dimension. The transfer is completed by completeTransfer once the client acknowledges the dimension change.
+func (s *Session) transfer(ctx context.Context, srv *server.Server) error {
+	conn, err := s.dial(ctx, srv)
+	if err != nil {
+		return transferError(ctx, fmt.Errorf("dial server %s: %w", srv.Address(), err))
+	}
+	if err := conn.DoSpawnContext(ctx); err != nil {
+		_ = conn.Close()
+		return transferError(ctx, fmt.Errorf("spawn on server %s: %w", srv.Address(), err))
+	}
+	if s.closed.Load() {
+		_ = conn.Close()
+		return ErrSessionClosed
+	}
+
+	s.serverMu.Lock()
+	s.tempServerConn = conn
+	s.serverMu.Unlock()
+
+	var proxyDimension int32
+	for _, dimension := range []int32{packet.DimensionOverworld, packet.DimensionNether, packet.DimensionEnd} {
+		if dimension != s.serverConn.GameData().Dimension && dimension != conn.GameData().Dimension {
+			proxyDimension = dimension
+			break
+		}
+	}
+
+	pos := s.conn.GameData().PlayerPosition
+	s.changeDimension(proxyDimension, pos)
+
+	chunkX := int32(pos.X()) >> 4
+	chunkZ := int32(pos.Z()) >> 4
+	for x := int32(-1); x <= 1; x++ {
+		for z := int32(-1); z <= 1; z++ {
+			_ = s.conn.WritePacket(&packet.LevelChunk{
+				Position:      protocol.ChunkPos{chunkX + x, chunkZ + z},
+				Dimension:     packet.DimensionNether,
+				SubChunkCount: 1,
+				RawPayload:    EmptyChunk(proxyDimension),
+			})
+		}
+	}
+
+	s.serverMu.Lock()
+	s.server.DecrementPlayerCount()
+	s.server = srv
+	s.server.IncrementPlayerCount()
+	s.serverMu.Unlock()
+	return nil
+}
+
+// transferError wraps the error passed with ErrTransferTimeout or ErrTransferCancelled if the context passed was
+// the cause of the error.
+func transferError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrTransferTimeout, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %v", ErrTransferCancelled, err)
+	}
+	return err
+}
+
+// completeTransfer completes the transfer of the session by moving the client back to the dimension of the new
+// server and replacing the server connection. It returns false if there was no transfer to complete.
+func (s *Session) completeTransfer() bool {
+	s.serverMu.Lock()
+	if s.tempServerConn == nil {
+		s.serverMu.Unlock()
+		return false
+	}
+	gameData := s.tempServerConn.GameData()
+	s.changeDimension(packet.DimensionOverworld, gameData.PlayerPosition)
+
+	var w sync.WaitGroup
+	w.Add(2)
+	go func() {
+		s.clearEntities()
+		s.clearEffects()
+		w.Done()
+	}()
+	go func() {
+		s.clearPlayerList()
+		s.clearBossBars()
+		s.clearScoreboard()
+		w.Done()
+	}()
+
+	_ = s.conn.WritePacket(&packet.MovePlayer{
+		EntityRuntimeID: s.originalRuntimeID,
+		Position:        gameData.PlayerPosition,
+		Pitch:           gameData.Pitch,
+		Yaw:             gameData.Yaw,
+		Mode:            packet.MoveModeReset,
+	})
+
+	_ = s.conn.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopRaining, EventData: 10000})
+	_ = s.conn.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopThunderstorm})
+	_ = s.conn.WritePacket(&packet.SetDifficulty{Difficulty: uint32(gameData.Difficulty)})
+	_ = s.conn.WritePacket(&packet.GameRulesChanged{GameRules: gameData.GameRules})
+	_ = s.conn.WritePacket(&packet.SetPlayerGameType{GameType: gameData.PlayerGameMode})
+
+	w.Wait()
+
+	_ = s.serverConn.Close()
+
+	s.serverConn = s.tempServerConn
+	s.tempServerConn = nil
+	s.serverMu.Unlock()
+
+	s.updateTranslatorData(gameData)
+
+	s.finishTransfer()
+	s.postTransfer.Store(true)
+
+	s.log.Infof("%s finished transferring to %s", s.conn.IdentityData().DisplayName, s.Server().Name())
+	return true
+}
+
+// Transferring returns if the session is currently transferring to a different server or not.
+func (s *Session) Transferring() bool {
+	return s.transferring.Load()
+}
+
+// startTransfer marks the session as transferring to a different server. It returns a channel that is closed once
+// the transfer has finished, or false if the session was already being transferred.
+func (s *Session) startTransfer() (<-chan struct{}, bool) {
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+	if s.transferDone != nil {
+		return nil, false
+	}
+	s.transferDone = make(chan struct{})
+	s.transferring.Store(true)
+	return s.transferDone, true
+}
+
+// finishTransfer marks the session as no longer transferring, releasing anything waiting for the transfer to
+// complete.
+func (s *Session) finishTransfer() {
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+	if s.transferDone != nil {
+		close(s.transferDone)
+		s.transferDone = nil
+	}
+	s.transferring.Store(false)
+}
+
+// waitForTransfer blocks until the transfer the session is currently going through, if any, has completed.
+func (s *Session) waitForTransfer() {
+	s.transferMu.Lock()
+	done := s.transferDone
+	s.transferMu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
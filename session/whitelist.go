@@ -1,8 +1,13 @@
 package session
 
 import (
+	"encoding/json"
 	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
 	"github.com/sandertv/gophertunnel/minecraft/text"
+	"os"
+	"strings"
+	"sync"
 )
 
 // Whitelist handles the players joining the proxy to decide which are allowed join.
@@ -36,3 +41,195 @@ func (s *SimpleWhitelist) Authorize(conn *minecraft.Conn) (bool, string) {
 	}
 	return false, text.Colourf("<red>Server is whitelisted</red>")
 }
+
+// WhitelistEntry is a player on a PersistentWhitelist.
+type WhitelistEntry struct {
+	// Name is the name of the player. It is used to match the player if their XUID is not known.
+	Name string `json:"name"`
+	// XUID is the XUID of the player. It is filled in the first time the player joins if it is empty.
+	XUID string `json:"xuid,omitempty"`
+}
+
+// PersistentWhitelist is a whitelist that may be changed at runtime. Players are matched by their XUID, or by
+// their name if their XUID is not known yet. The whitelist is saved to a JSON file every time it changes, except by
+// Authorize, which never writes to the file.
+type PersistentWhitelist struct {
+	path string
+
+	mu      sync.Mutex
+	enabled bool
+	message string
+	players []WhitelistEntry
+}
+
+// persistentWhitelistData is the data of a PersistentWhitelist that is saved to its file.
+type persistentWhitelistData struct {
+	Enabled bool             `json:"enabled"`
+	Players []WhitelistEntry `json:"players"`
+}
+
+// NewPersistentWhitelist creates a whitelist that is loaded from and saved to the file at the path passed. If the
+// file does not exist yet, the whitelist is created using the enabled status and player names passed. The message
+// passed is shown to players that are not whitelisted, and a default message is used if it is empty.
+func NewPersistentWhitelist(path string, enabled bool, players []string, message string) (*PersistentWhitelist, error) {
+	w := &PersistentWhitelist{path: path, enabled: enabled}
+	w.SetMessage(message)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		for _, name := range players {
+			w.players = append(w.players, WhitelistEntry{Name: name})
+		}
+		return w, w.save()
+	} else if err != nil {
+		return nil, err
+	}
+	var d persistentWhitelistData
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	w.enabled, w.players = d.Enabled, d.Players
+	return w, nil
+}
+
+// Authorize ...
+func (w *PersistentWhitelist) Authorize(conn *minecraft.Conn) (bool, string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.enabled {
+		return true, ""
+	}
+
+	if _, ok := w.match(conn.IdentityData()); ok {
+		return true, ""
+	}
+	return false, w.message
+}
+
+// BindXUID remembers the XUID of the player passed if they are whitelisted by their name only, so that they are
+// still whitelisted after changing their name, and saves the whitelist if it changed. It is called by the proxy
+// after the player was authorized, so that Authorize does not have to write to the file.
+func (w *PersistentWhitelist) BindXUID(identity login.IdentityData) error {
+	if identity.XUID == "" {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	i, ok := w.match(identity)
+	if !ok || w.players[i].XUID != "" {
+		return nil
+	}
+	w.players[i].XUID = identity.XUID
+	return w.save()
+}
+
+// Enabled returns if the whitelist is enabled.
+func (w *PersistentWhitelist) Enabled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enabled
+}
+
+// SetEnabled enables or disables the whitelist and saves it.
+func (w *PersistentWhitelist) SetEnabled(enabled bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enabled = enabled
+	return w.save()
+}
+
+// Message returns the message shown to players that are not whitelisted.
+func (w *PersistentWhitelist) Message() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.message
+}
+
+// SetMessage sets the message shown to players that are not whitelisted. If it is empty, a default message is used.
+func (w *PersistentWhitelist) SetMessage(message string) {
+	if message == "" {
+		message = text.Colourf("<red>Server is whitelisted</red>")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.message = message
+}
+
+// Players returns all the entries on the whitelist.
+func (w *PersistentWhitelist) Players() []WhitelistEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]WhitelistEntry(nil), w.players...)
+}
+
+// Add adds a player to the whitelist and saves it. The XUID may be empty, in which case the player is matched by
+// name until they join for the first time. If the player is already whitelisted, their entry is updated.
+func (w *PersistentWhitelist) Add(name, xuid string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if i, ok := w.index(name, xuid); ok {
+		w.players[i].Name = name
+		if xuid != "" {
+			w.players[i].XUID = xuid
+		}
+	} else {
+		w.players = append(w.players, WhitelistEntry{Name: name, XUID: xuid})
+	}
+	return w.save()
+}
+
+// Remove removes the player with the name or XUID passed from the whitelist and saves it. False is returned if the
+// player was not whitelisted.
+func (w *PersistentWhitelist) Remove(nameOrXUID string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	i, ok := w.index(nameOrXUID, nameOrXUID)
+	if !ok {
+		return false, nil
+	}
+	w.players = append(w.players[:i:i], w.players[i+1:]...)
+	return true, w.save()
+}
+
+// match returns the index of the entry that whitelists the player passed, and if it was found. Entries with an XUID
+// only match that XUID, while other entries match the name of the player.
+func (w *PersistentWhitelist) match(identity login.IdentityData) (int, bool) {
+	for i, e := range w.players {
+		if e.XUID != "" {
+			if e.XUID == identity.XUID {
+				return i, true
+			}
+			continue
+		}
+		if strings.EqualFold(e.Name, identity.DisplayName) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// index returns the index of the entry with the name or XUID passed, and if it was found.
+func (w *PersistentWhitelist) index(name, xuid string) (int, bool) {
+	for i, e := range w.players {
+		if (xuid != "" && e.XUID == xuid) || strings.EqualFold(e.Name, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// save saves the whitelist to its file.
+func (w *PersistentWhitelist) save() error {
+	players := w.players
+	if players == nil {
+		players = []WhitelistEntry{}
+	}
+	data, err := json.MarshalIndent(persistentWhitelistData{Enabled: w.enabled, Players: players}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.path, data, 0644)
+}
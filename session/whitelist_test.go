@@ -0,0 +1,103 @@
+package session
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPersistentWhitelist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whitelist.json")
+	w, err := NewPersistentWhitelist(path, true, []string{"Steve", "Alex"}, "")
+	if err != nil {
+		t.Fatalf("NewPersistentWhitelist() error = %v", err)
+	}
+	if err := w.Add("Notch", "2535400000000003"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if ok, err := w.Remove("alex"); !ok || err != nil {
+		t.Fatalf("Remove() = %v, %v, want true, nil", ok, err)
+	}
+	if err := w.BindXUID(login.IdentityData{DisplayName: "steve", XUID: "2535400000000001"}); err != nil {
+		t.Fatalf("BindXUID() error = %v", err)
+	}
+
+	// The whitelist is loaded again from its file, so that only the changes that were saved are tested.
+	w, err = NewPersistentWhitelist(path, false, nil, "")
+	if err != nil {
+		t.Fatalf("NewPersistentWhitelist() error = %v", err)
+	}
+	if !w.Enabled() {
+		t.Errorf("Enabled() = false, want the saved value true")
+	}
+	want := []WhitelistEntry{{Name: "Steve", XUID: "2535400000000001"}, {Name: "Notch", XUID: "2535400000000003"}}
+	if players := w.Players(); !reflect.DeepEqual(players, want) {
+		t.Errorf("Players() = %v, want %v", players, want)
+	}
+
+	tests := []struct {
+		name     string
+		identity login.IdentityData
+		want     bool
+	}{
+		{name: "bound xuid", identity: login.IdentityData{DisplayName: "Steve", XUID: "2535400000000001"}, want: true},
+		{name: "bound xuid after name change", identity: login.IdentityData{DisplayName: "Herobrine", XUID: "2535400000000001"}, want: true},
+		{name: "name of bound entry", identity: login.IdentityData{DisplayName: "Steve", XUID: "2535400000000002"}, want: false},
+		{name: "removed", identity: login.IdentityData{DisplayName: "Alex"}, want: false},
+		{name: "added with xuid", identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000003"}, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, ok := w.match(test.identity); ok != test.want {
+				t.Errorf("match() = %v, want %v", ok, test.want)
+			}
+		})
+	}
+}
+
+func TestBindXUID(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity login.IdentityData
+		want     []WhitelistEntry
+	}{
+		{
+			name:     "bound by name",
+			identity: login.IdentityData{DisplayName: "STEVE", XUID: "2535400000000001"},
+			want:     []WhitelistEntry{{Name: "Steve", XUID: "2535400000000001"}, {Name: "Alex", XUID: "2535400000000002"}},
+		},
+		{
+			name:     "offline player",
+			identity: login.IdentityData{DisplayName: "Steve"},
+			want:     []WhitelistEntry{{Name: "Steve"}, {Name: "Alex", XUID: "2535400000000002"}},
+		},
+		{
+			name:     "already bound",
+			identity: login.IdentityData{DisplayName: "Alex", XUID: "2535400000000009"},
+			want:     []WhitelistEntry{{Name: "Steve"}, {Name: "Alex", XUID: "2535400000000002"}},
+		},
+		{
+			name:     "not whitelisted",
+			identity: login.IdentityData{DisplayName: "Notch", XUID: "2535400000000003"},
+			want:     []WhitelistEntry{{Name: "Steve"}, {Name: "Alex", XUID: "2535400000000002"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := NewPersistentWhitelist(filepath.Join(t.TempDir(), "whitelist.json"), true, []string{"Steve"}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Add("Alex", "2535400000000002"); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.BindXUID(test.identity); err != nil {
+				t.Fatalf("BindXUID() error = %v", err)
+			}
+			if players := w.Players(); !reflect.DeepEqual(players, test.want) {
+				t.Errorf("Players() = %v, want %v", players, test.want)
+			}
+		})
+	}
+}
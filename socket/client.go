@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"github.com/paroxity/portal/internal"
+	"github.com/paroxity/portal/logging"
 	"github.com/paroxity/portal/socket/packet"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"go.uber.org/atomic"
@@ -14,7 +14,7 @@ import (
 
 // Client represents a client connected over the TCP socket system.
 type Client struct {
-	log  internal.Logger
+	log  logging.Logger
 	conn net.Conn
 
 	readerLimits bool
@@ -31,7 +31,7 @@ type Client struct {
 
 // NewClient creates a new socket Client with default allocations and required data. It pre-allocates 4096
 // bytes to prevent allocations during runtime as much as possible.
-func NewClient(conn net.Conn, log internal.Logger, readerLimits bool) *Client {
+func NewClient(conn net.Conn, log logging.Logger, readerLimits bool) *Client {
 	return &Client{
 		log:  log,
 		conn: conn,
@@ -11,7 +11,13 @@ type RegisterServerHandler struct{ requireAuth }
 // Handle ...
 func (*RegisterServerHandler) Handle(p packet.Packet, srv Server, c *Client) error {
 	pk := p.(*packet.RegisterServer)
-	srv.ServerRegistry().AddServer(server.New(c.Name(), pk.Address))
-	srv.Logger().Debugf("socket connection \"%s\" has registered itself as a server with the address \"%s\"", c.Name(), pk.Address)
+	if existing, ok := srv.ServerRegistry().Server(c.Name()); ok && existing.Static() {
+		srv.Logger().Errorf("socket connection \"%s\" tried to register itself as a server, but a static server with that name already exists", c.Name())
+		return nil
+	}
+	s := server.NewInGroup(c.Name(), pk.Group, pk.Address)
+	s.SetMaxPlayers(int(pk.MaxPlayers))
+	srv.ServerRegistry().AddServer(s)
+	srv.Logger().Debugf("socket connection \"%s\" has registered itself as a server in group \"%s\" with the address \"%s\"", c.Name(), pk.Group, pk.Address)
 	return nil
 }
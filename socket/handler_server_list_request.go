@@ -14,6 +14,7 @@ func (*ServerListRequestHandler) Handle(_ packet.Packet, srv Server, c *Client)
 	for _, s := range srv.ServerRegistry().Servers() {
 		entry := packet.ServerEntry{
 			Name:        s.Name(),
+			Group:       s.Group(),
 			PlayerCount: int64(s.PlayerCount()),
 		}
 		servers = append(servers, entry)
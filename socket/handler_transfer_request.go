@@ -1,9 +1,20 @@
 package socket
 
 import (
+	"context"
+	"errors"
+	"github.com/paroxity/portal/queue"
+	"github.com/paroxity/portal/server"
+	"github.com/paroxity/portal/session"
 	"github.com/paroxity/portal/socket/packet"
+	"strings"
+	"time"
 )
 
+// transferTimeout is the maximum time a transfer requested by a socket client may take to dial and spawn on the new
+// server.
+const transferTimeout = time.Second * 30
+
 // TransferRequestHandler is responsible for handling the TransferRequest packet sent by servers.
 type TransferRequestHandler struct{ requireAuth }
 
@@ -18,23 +29,72 @@ func (*TransferRequestHandler) Handle(p packet.Packet, srv Server, c *Client) er
 		})
 	}
 
-	targetSrv, ok := srv.ServerRegistry().Server(pk.Server)
-	if !ok {
-		return response(packet.TransferResponseServerNotFound, "")
-	}
-
 	s, ok := srv.SessionStore().Load(pk.PlayerUUID)
 	if !ok {
 		return response(packet.TransferResponsePlayerNotFound, "")
 	}
 
+	var targetSrv *server.Server
+	if pk.Server == "" && pk.Group != "" {
+		servers := srv.ServerRegistry().ServersInGroup(pk.Group)
+		if len(servers) == 0 {
+			return response(packet.TransferResponseGroupNotFound, "")
+		}
+		targetSrv = session.FindServerInGroup(srv.LoadBalancer(), s, pk.Group)
+		if targetSrv == nil {
+			if len(servers) == 1 && servers[0] == s.Server() {
+				return response(packet.TransferResponseAlreadyOnServer, "")
+			}
+			if q := srv.Queue(); q != nil && !strings.EqualFold(s.Server().Group(), pk.Group) {
+				q.Enqueue(s, queue.Target{Group: pk.Group})
+				return response(packet.TransferResponseQueued, "")
+			}
+			// Every other server in the group is either unhealthy or full.
+			return response(packet.TransferResponseNoServerAvailable, "")
+		}
+	} else {
+		targetSrv, ok = srv.ServerRegistry().Server(pk.Server)
+		if !ok {
+			return response(packet.TransferResponseServerNotFound, "")
+		}
+	}
+
 	if s.Server().Address() == targetSrv.Address() {
 		return response(packet.TransferResponseAlreadyOnServer, "")
 	}
-
-	if err := s.Transfer(targetSrv); err != nil {
-		return response(packet.TransferResponseError, err.Error())
+	if !targetSrv.Healthy() {
+		return response(packet.TransferResponseServerUnhealthy, "")
 	}
+	if targetSrv.Full() && !s.BypassesCapacity() {
+		if q := srv.Queue(); q != nil {
+			q.Enqueue(s, queue.Target{Server: targetSrv.Name()})
+			return response(packet.TransferResponseQueued, "")
+		}
+		return response(packet.TransferResponseServerFull, "")
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), transferTimeout)
+		defer cancel()
 
-	return response(packet.TransferResponseSuccess, "")
+		var err error
+		switch transferErr := s.TransferContext(ctx, targetSrv); {
+		case transferErr == nil:
+			err = response(packet.TransferResponseSuccess, "")
+		case errors.Is(transferErr, session.ErrTransferCancelled):
+			err = response(packet.TransferResponseCancelled, "")
+		case errors.Is(transferErr, session.ErrTransferTimeout):
+			err = response(packet.TransferResponseTimeout, "")
+		case errors.Is(transferErr, session.ErrServerUnhealthy):
+			err = response(packet.TransferResponseServerUnhealthy, "")
+		case errors.Is(transferErr, session.ErrServerFull):
+			err = response(packet.TransferResponseServerFull, "")
+		default:
+			err = response(packet.TransferResponseError, transferErr.Error())
+		}
+		if err != nil {
+			srv.Logger().Errorf("socket server unable to send transfer response: %v", err)
+		}
+	}()
+	return nil
 }
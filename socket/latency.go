@@ -5,9 +5,18 @@ import (
 	"time"
 )
 
-// ReportPlayerLatency sends the latency of each player to their connected server at the interval provided.
+// ReportPlayerLatency sends the latency of each player to their connected server at the interval provided. It
+// blocks until the socket server is closed. The interval may be changed while reporting using
+// SetPlayerLatencyInterval.
 func (s *DefaultServer) ReportPlayerLatency(interval time.Duration) {
+	s.latencyInterval.Store(interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
 	for {
+		if v := s.latencyInterval.Load(); v != interval {
+			interval = v
+			t.Reset(interval)
+		}
 		for _, session := range s.SessionStore().All() {
 			srv := session.Server()
 			if srv == nil {
@@ -24,6 +33,18 @@ func (s *DefaultServer) ReportPlayerLatency(interval time.Duration) {
 				s.Logger().Errorf("failed to send packet: %v", err)
 			}
 		}
-		time.Sleep(interval)
+		select {
+		case <-t.C:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// SetPlayerLatencyInterval changes the interval at which the latency of each player is reported by
+// ReportPlayerLatency. It takes effect after the next report.
+func (s *DefaultServer) SetPlayerLatencyInterval(interval time.Duration) {
+	if interval > 0 {
+		s.latencyInterval.Store(interval)
 	}
 }
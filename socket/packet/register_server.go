@@ -6,6 +6,12 @@ import "github.com/sandertv/gophertunnel/minecraft/protocol"
 type RegisterServer struct {
 	// Address is the address of the server in the format ip:port.
 	Address string
+	// Group is the name of the group the server should be registered in. It may be empty if the server is not
+	// part of a group.
+	Group string
+	// MaxPlayers is the maximum amount of players that may be connected to the server. If zero, the amount of
+	// players is not limited.
+	MaxPlayers int32
 }
 
 // ID ...
@@ -16,9 +22,13 @@ func (pk *RegisterServer) ID() uint16 {
 // Marshal ...
 func (pk *RegisterServer) Marshal(w *protocol.Writer) {
 	w.String(&pk.Address)
+	w.String(&pk.Group)
+	w.Int32(&pk.MaxPlayers)
 }
 
 // Unmarshal ...
 func (pk *RegisterServer) Unmarshal(r *protocol.Reader) {
 	r.String(&pk.Address)
+	r.String(&pk.Group)
+	r.Int32(&pk.MaxPlayers)
 }
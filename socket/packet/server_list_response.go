@@ -15,6 +15,8 @@ type ServerListResponse struct {
 type ServerEntry struct {
 	// Name is name of the server.
 	Name string
+	// Group is the name of the group the server is in.
+	Group string
 	// PlayerCount returns player count of the server.
 	PlayerCount int64
 }
@@ -31,6 +33,7 @@ func (pk *ServerListResponse) Marshal(w *protocol.Writer) {
 
 	for _, s := range pk.Servers {
 		w.String(&s.Name)
+		w.String(&s.Group)
 		w.Int64(&s.PlayerCount)
 	}
 }
@@ -43,6 +46,7 @@ func (pk *ServerListResponse) Unmarshal(r *protocol.Reader) {
 	pk.Servers = make([]ServerEntry, l)
 	for i := uint32(0); i < l; i++ {
 		r.String(&pk.Servers[i].Name)
+		r.String(&pk.Servers[i].Group)
 		r.Int64(&pk.Servers[i].PlayerCount)
 	}
 }
@@ -9,8 +9,12 @@ import (
 type TransferRequest struct {
 	// PlayerUUID is the UUID of the player to be transferred.
 	PlayerUUID uuid.UUID
-	// Server is the name of the server in the group to transfer to.
+	// Server is the name of the server to transfer to. If empty, the player is transferred to a server in
+	// the group below instead.
 	Server string
+	// Group is the name of the group to transfer to. The load balancer picks the server within the group the
+	// player is transferred to. It is only used if Server is empty.
+	Group string
 }
 
 // ID ...
@@ -22,10 +26,12 @@ func (*TransferRequest) ID() uint16 {
 func (pk *TransferRequest) Marshal(w *protocol.Writer) {
 	w.UUID(&pk.PlayerUUID)
 	w.String(&pk.Server)
+	w.String(&pk.Group)
 }
 
 // Unmarshal ...
 func (pk *TransferRequest) Unmarshal(r *protocol.Reader) {
 	r.UUID(&pk.PlayerUUID)
 	r.String(&pk.Server)
+	r.String(&pk.Group)
 }
@@ -11,13 +11,21 @@ const (
 	TransferResponseAlreadyOnServer
 	TransferResponsePlayerNotFound
 	TransferResponseError
+	TransferResponseGroupNotFound
+	TransferResponseServerUnhealthy
+	TransferResponseServerFull
+	TransferResponseQueued
+	TransferResponseCancelled
+	TransferResponseTimeout
+	TransferResponseNoServerAvailable
 )
 
 // TransferResponse is sent by the proxy in response to a transfer request.
 type TransferResponse struct {
 	// PlayerUUID is the UUID of the player being transferred.
 	PlayerUUID uuid.UUID
-	// Status is the response status from the transfer. The possible values for this can be found above.
+	// Status is the response status from the transfer. The possible values for this can be found above. It is
+	// sent once the transfer has completed or failed.
 	Status byte
 	// Error is the error message when the Status field is TransferResponseError.
 	Error string
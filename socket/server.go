@@ -1,10 +1,12 @@
 package socket
 
 import (
-	"github.com/paroxity/portal/internal"
+	"github.com/paroxity/portal/logging"
+	"github.com/paroxity/portal/queue"
 	"github.com/paroxity/portal/server"
 	"github.com/paroxity/portal/session"
 	"github.com/paroxity/portal/socket/packet"
+	"go.uber.org/atomic"
 	"net"
 	"strings"
 	"sync"
@@ -13,9 +15,11 @@ import (
 type Server interface {
 	// Listen starts listening for connections on an address.
 	Listen() error
+	// Close stops listening for connections and closes all the connected clients.
+	Close() error
 
 	// Logger returns the logger attached to the socket server.
-	Logger() internal.Logger
+	Logger() logging.Logger
 
 	// Secret returns the secret required for connections to authenticate.
 	Secret() string
@@ -27,46 +31,81 @@ type Server interface {
 	// Authenticate marks the client as authenticated with the provided name. It is safe to assume that the provided
 	// name is not in use, unless called by places other than the socket server.
 	Authenticate(c *Client, name string)
+	// Handle sets the handler of the socket server, which is called when clients authenticate. If nil is passed,
+	// the handler is reset to a NopServerHandler.
+	Handle(h ServerHandler)
+	// Handler returns the handler of the socket server, which is a NopServerHandler if no handler has been set.
+	Handler() ServerHandler
+	// AddMonitor adds a handler that is called after the handler set using Handle whenever a client authenticates.
+	// Unlike that handler, monitors are not replaced by calls to Handle.
+	AddMonitor(h ServerHandler)
 
 	// SessionStore returns the store used to hold the open sessions on the proxy.
 	SessionStore() *session.Store
 	// ServerRegistry returns the registry used to store available servers on the proxy.
 	ServerRegistry() *server.Registry
+	// LoadBalancer returns the load balancer used to pick a server when a player is transferred to a group.
+	LoadBalancer() session.LoadBalancer
+	// Queue returns the queue players are added to when they are transferred to a full server or group. It may
+	// be nil if players should not be queued.
+	Queue() *queue.Queue
 }
 
 // DefaultServer represents a basic TCP socket server implementation. It allows external connections to
 // connect and authenticate to be able to communicate with the proxy.
 type DefaultServer struct {
-	log internal.Logger
+	log logging.Logger
 
 	addr         string
-	secret       string
+	secret       atomic.String
 	readerLimits bool
 
 	listener           net.Listener
+	listening          atomic.Bool
 	clientsMu          sync.RWMutex
 	clients            map[string]*Client
 	unconnectedClients map[net.Addr]*Client
 
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	latencyInterval atomic.Duration
+
+	hMutex   sync.RWMutex
+	h        ServerHandler
+	monitors []ServerHandler
+
 	sessionStore   *session.Store
 	serverRegistry *server.Registry
+	queue          atomic.Pointer[queue.Queue]
+
+	loadBalancerMu sync.RWMutex
+	loadBalancer   session.LoadBalancer
 }
 
-// NewDefaultServer creates a new default server to be used for accepting socket connections.
-func NewDefaultServer(addr, secret string, sessionStore *session.Store, serverRegistry *server.Registry, log internal.Logger, readerLimits bool) *DefaultServer {
-	return &DefaultServer{
+// NewDefaultServer creates a new default server to be used for accepting socket connections. Servers in a group
+// that players are transferred to are picked by a split load balancer, unless another load balancer is set using
+// SetLoadBalancer.
+func NewDefaultServer(addr, secret string, sessionStore *session.Store, serverRegistry *server.Registry, log logging.Logger, readerLimits bool) *DefaultServer {
+	s := &DefaultServer{
 		log: log,
 
 		addr:         addr,
-		secret:       secret,
 		readerLimits: readerLimits,
 
 		clients:            make(map[string]*Client),
 		unconnectedClients: make(map[net.Addr]*Client),
 
+		closed: make(chan struct{}),
+
+		h: NopServerHandler{},
+
 		sessionStore:   sessionStore,
 		serverRegistry: serverRegistry,
+		loadBalancer:   session.NewSplitLoadBalancer(serverRegistry),
 	}
+	s.secret.Store(secret)
+	return s
 }
 
 // Listen ...
@@ -77,11 +116,17 @@ func (s *DefaultServer) Listen() error {
 	}
 	s.log.Infof("socket server listening on %s\n", s.addr)
 	s.listener = listener
+	s.listening.Store(true)
 
 	go func() {
 		for {
 			conn, err := listener.Accept()
 			if err != nil {
+				select {
+				case <-s.closed:
+					return
+				default:
+				}
 				s.log.Infof("socket server unable to accept connection: %v", err)
 				continue
 			}
@@ -93,6 +138,33 @@ func (s *DefaultServer) Listen() error {
 	return nil
 }
 
+// Close ...
+func (s *DefaultServer) Close() (err error) {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.listening.Store(false)
+		if s.listener != nil {
+			err = s.listener.Close()
+		}
+
+		s.clientsMu.RLock()
+		clients := make([]*Client, 0, len(s.clients)+len(s.unconnectedClients))
+		for _, c := range s.clients {
+			clients = append(clients, c)
+		}
+		for _, c := range s.unconnectedClients {
+			clients = append(clients, c)
+		}
+		s.clientsMu.RUnlock()
+
+		for _, c := range clients {
+			_ = c.Close()
+		}
+		s.log.Infof("socket server closed")
+	})
+	return
+}
+
 // handleClient handles a client that has been accepted from the socket server.
 func (s *DefaultServer) handleClient(c *Client) {
 	defer s.handleClientDisconnect(c)
@@ -138,20 +210,32 @@ func (s *DefaultServer) handleClientDisconnect(c *Client) {
 	delete(s.unconnectedClients, c.conn.RemoteAddr())
 	s.log.Debugf("socket connection \"%s\" closed", c.name)
 	srv, ok := s.serverRegistry.Server(c.Name())
-	if ok {
+	if ok && !srv.Static() {
 		s.serverRegistry.RemoveServer(srv)
 		s.log.Debugf("removed server for socket connection \"%s\"", c.Name())
 	}
 }
 
+// Listening returns if the socket server is listening for connections. It is false before Listen is called and
+// after the server is closed.
+func (s *DefaultServer) Listening() bool {
+	return s.listening.Load()
+}
+
 // Logger ...
-func (s *DefaultServer) Logger() internal.Logger {
+func (s *DefaultServer) Logger() logging.Logger {
 	return s.log
 }
 
 // Secret ...
 func (s *DefaultServer) Secret() string {
-	return s.secret
+	return s.secret.Load()
+}
+
+// SetSecret sets the secret required for connections to authenticate. Clients that have already authenticated
+// stay connected.
+func (s *DefaultServer) SetSecret(secret string) {
+	s.secret.Store(secret)
 }
 
 // Clients ...
@@ -175,10 +259,42 @@ func (s *DefaultServer) Client(name string) (*Client, bool) {
 // Authenticate ...
 func (s *DefaultServer) Authenticate(c *Client, name string) {
 	s.clientsMu.Lock()
-	defer s.clientsMu.Unlock()
 	delete(s.unconnectedClients, c.conn.RemoteAddr())
 	s.clients[name] = c
 	c.Authenticate(name)
+	s.clientsMu.Unlock()
+
+	s.Handler().HandleClientAuthenticate(c)
+	s.hMutex.RLock()
+	monitors := s.monitors
+	s.hMutex.RUnlock()
+	for _, h := range monitors {
+		h.HandleClientAuthenticate(c)
+	}
+}
+
+// Handle ...
+func (s *DefaultServer) Handle(h ServerHandler) {
+	if h == nil {
+		h = NopServerHandler{}
+	}
+	s.hMutex.Lock()
+	defer s.hMutex.Unlock()
+	s.h = h
+}
+
+// Handler ...
+func (s *DefaultServer) Handler() ServerHandler {
+	s.hMutex.RLock()
+	defer s.hMutex.RUnlock()
+	return s.h
+}
+
+// AddMonitor ...
+func (s *DefaultServer) AddMonitor(h ServerHandler) {
+	s.hMutex.Lock()
+	defer s.hMutex.Unlock()
+	s.monitors = append(s.monitors, h)
 }
 
 // SessionStore ...
@@ -191,6 +307,32 @@ func (s *DefaultServer) ServerRegistry() *server.Registry {
 	return s.serverRegistry
 }
 
+// LoadBalancer ...
+func (s *DefaultServer) LoadBalancer() session.LoadBalancer {
+	s.loadBalancerMu.RLock()
+	defer s.loadBalancerMu.RUnlock()
+	return s.loadBalancer
+}
+
+// SetLoadBalancer sets the load balancer used to pick a server when a player is transferred to a group. It should
+// usually be the load balancer of the proxy.
+func (s *DefaultServer) SetLoadBalancer(loadBalancer session.LoadBalancer) {
+	s.loadBalancerMu.Lock()
+	defer s.loadBalancerMu.Unlock()
+	s.loadBalancer = loadBalancer
+}
+
+// Queue ...
+func (s *DefaultServer) Queue() *queue.Queue {
+	return s.queue.Load()
+}
+
+// SetQueue sets the queue players are added to when they are transferred to a full server or group. If nil, the
+// transfer is refused instead.
+func (s *DefaultServer) SetQueue(q *queue.Queue) {
+	s.queue.Store(q)
+}
+
 // containsAny checks if the string contains any of the provided sub strings.
 func containsAny(s string, subs ...string) bool {
 	for _, sub := range subs {
@@ -0,0 +1,13 @@
+package socket
+
+// ServerHandler handles events that are called by a socket server.
+type ServerHandler interface {
+	// HandleClientAuthenticate handles a client that has successfully authenticated with the socket server.
+	HandleClientAuthenticate(c *Client)
+}
+
+// NopServerHandler represents a ServerHandler that does nothing.
+type NopServerHandler struct{}
+
+// HandleClientAuthenticate ...
+func (NopServerHandler) HandleClientAuthenticate(*Client) {}
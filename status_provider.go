@@ -30,3 +30,19 @@ func (p *MOTDStatusProvider) ServerStatus(playerCount, maxPlayers int) minecraft
 		MaxPlayers:  maxPlayers,
 	}
 }
+
+// maintenanceStatusProvider is a status provider that shows the MOTD of the maintenance mode of the proxy while it
+// is enabled, and the status of the provider it wraps otherwise.
+type maintenanceStatusProvider struct {
+	maintenance *Maintenance
+	provider    minecraft.ServerStatusProvider
+}
+
+// ServerStatus ...
+func (p maintenanceStatusProvider) ServerStatus(playerCount, maxPlayers int) minecraft.ServerStatus {
+	status := p.provider.ServerStatus(playerCount, maxPlayers)
+	if p.maintenance.Enabled() {
+		status.ServerName = p.maintenance.MOTD()
+	}
+	return status
+}